@@ -4,7 +4,10 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"io"
+	"os"
+	"path/filepath"
+	goruntime "runtime"
+	"strings"
 	"sync"
 
 	"github.com/opencontainers/image-spec/identity"
@@ -21,6 +24,65 @@ import (
 const outputLineLength = 200
 const outputLineCount = 100
 
+// binfmtMiscDir is the directory with the registered binary formats of the kernel
+const binfmtMiscDir = "/proc/sys/fs/binfmt_misc"
+
+// qemuArchitectures maps the architectures of image platforms to the qemu-user emulators
+var qemuArchitectures = map[string]string{
+	"amd64":    "x86_64",
+	"386":      "i386",
+	"arm64":    "aarch64",
+	"arm":      "arm",
+	"ppc64le":  "ppc64le",
+	"s390x":    "s390x",
+	"riscv64":  "riscv64",
+	"mips64le": "mips64el",
+}
+
+// nativeArchitectures lists the architectures that can run natively on the host architecture
+var nativeArchitectures = map[string][]string{
+	"amd64": {"386"},
+	"arm64": {"arm"},
+}
+
+// platformEmulator returns the name of the qemu-user emulator required for running the
+// binaries of the platform in the format os/arch[/variant] on the host architecture, or an
+// empty string if the binaries run natively.
+func platformEmulator(hostArch, platform string) string {
+
+	parts := strings.Split(platform, "/")
+	if len(parts) < 2 || parts[1] == hostArch {
+		return ""
+	}
+	arch := parts[1]
+	for _, a := range nativeArchitectures[hostArch] {
+		if a == arch {
+			return ""
+		}
+	}
+	if name, ok := qemuArchitectures[arch]; ok {
+		return "qemu-" + name
+	}
+	return "qemu-" + arch
+}
+
+// checkPlatform checks that the binaries of the platform can run on the host, either natively
+// or through a qemu-user emulator registered with binfmt_misc.
+func checkPlatform(platform string) error {
+
+	emulator := platformEmulator(goruntime.GOARCH, platform)
+	if emulator == "" {
+		return nil
+	}
+	status, err := os.ReadFile(filepath.Join(binfmtMiscDir, emulator))
+	if err != nil || !strings.HasPrefix(string(status), "enabled") {
+		return errdefs.InvalidArgument(
+			"platform '%s' requires the emulator %s registered with binfmt_misc",
+			platform, emulator)
+	}
+	return nil
+}
+
 // getContainer returns the existing active container for the workspace or
 // creates the container and outputs progress status.
 // Note that in an error case, it will keep any residual container and snapshots.
@@ -33,10 +95,11 @@ func getContainer(ctx context.Context,
 		return nil, nil, errdefs.InvalidArgument("Workspace has no image defined")
 	}
 
-	img, err := run.GetImage(ctx, ws.Environment.Origin)
-	if err != nil && errors.Is(err, errdefs.ErrNotFound) {
-		img, err = pullImage(ctx, run, ws.Environment.Origin)
+	img, err := pullOriginImage(ctx, run, ws)
+	if err != nil {
+		return nil, nil, err
 	}
+	err = checkPlatform(img.Platform())
 	if err != nil {
 		return nil, nil, err
 	}
@@ -93,18 +156,28 @@ func buildLayers(ctx context.Context, run runtime.Runtime, ctr runtime.Container
 		showProgress(progress)
 	}()
 
-	rb := NewRingBuffer(outputLineCount, outputLineLength)
-	stream := rb.StreamWriter()
-
-	err := container.Build(ctx, run, ctr, img, ws, layerCount, &user, &params, progress, stream)
+	bl := newBuildLog(ws)
+	err := container.Build(ctx, run, ctr, img, ws, layerCount, &user, &params, progress,
+		bl.layerStream)
 	if err != nil && errors.Is(err, errdefs.ErrCommandFailed) {
-		line := make([]byte, 100)
-		fmt.Printf("Output:\n")
-		for _, err := rb.Read(line); err != io.EOF; _, err = rb.Read(line) {
-			fmt.Printf(" > %v\n", string(line))
-		}
+		bl.printTail()
+	}
+	bl.close(ws, err == nil)
+	return err
+}
+
+// buildBase builds the base workspaces of the provided workspace if they haven't been built.
+func buildBase(ctx context.Context, run runtime.Runtime, ws *project.Workspace) error {
+
+	base, err := ws.BaseWorkspace()
+	if err != nil || base == nil {
 		return err
 	}
+
+	_, err = container.GetContainer(ctx, run, base)
+	if err != nil && errors.Is(err, errdefs.ErrNotFound) {
+		_, err = buildContainer(ctx, run, base, -1)
+	}
 	return err
 }
 
@@ -113,6 +186,11 @@ func buildLayers(ctx context.Context, run runtime.Runtime, ctr runtime.Container
 func buildContainer(ctx context.Context, run runtime.Runtime, ws *project.Workspace,
 	layerCount int) (runtime.Container, error) {
 
+	err := buildBase(ctx, run, ws)
+	if err != nil {
+		return nil, err
+	}
+
 	progress := make(chan []runtime.ProgressStatus)
 	var wg sync.WaitGroup
 	defer wg.Wait()
@@ -124,6 +202,7 @@ func buildContainer(ctx context.Context, run runtime.Runtime, ws *project.Worksp
 	}()
 
 	params.Upgrade = buildWorkspaceUpgrade
+	params.NoCache = buildWorkspaceNoCache || buildWorkspaceForce || buildWorkspaceFrom != ""
 	ctr, img, err := getContainer(ctx, run, ws, progress)
 	if err != nil {
 		return nil, err
@@ -161,6 +240,50 @@ var buildWorkspaceCmd = &cobra.Command{
 
 var buildWorkspaceForce bool
 var buildWorkspaceUpgrade string
+var buildWorkspaceNoCache bool
+var buildWorkspaceFrom string
+var buildDryRun bool
+
+// showBuildPlan prints which layers are cached and which layers would be built with the
+// expanded commands.
+func showBuildPlan(ctx context.Context, run runtime.Runtime, ws *project.Workspace) error {
+
+	img, err := localOriginImage(ctx, run, ws)
+	if err != nil && !errors.Is(err, errdefs.ErrNotFound) {
+		return err
+	}
+	if err != nil {
+		fmt.Printf("Image '%s' will be pulled\n", ws.OriginReference())
+		img = nil
+	}
+
+	params.Upgrade = buildWorkspaceUpgrade
+	params.NoCache = buildWorkspaceNoCache || buildWorkspaceForce || buildWorkspaceFrom != ""
+	rootName, plan, err := container.Plan(ctx, run, img, ws, -1, &user, &params)
+	if err != nil {
+		return err
+	}
+
+	if rootName != "" {
+		fmt.Printf("Build on snapshot %s\n", rootName)
+	}
+	for _, l := range plan {
+		status := "build"
+		switch l.Status {
+		case runtime.StatusCached:
+			status = "cached"
+		case runtime.StatusSkipped:
+			status = "disabled"
+		case runtime.StatusError:
+			status = "snapshot layer must be committed again"
+		}
+		fmt.Printf("Layer %s: %s\n", l.Name, status)
+		for _, args := range l.Commands {
+			fmt.Printf("    %s\n", strings.Join(args, " "))
+		}
+	}
+	return nil
+}
 
 func buildWorkspaceRunE(cmd *cobra.Command, args []string) error {
 
@@ -191,13 +314,30 @@ func buildWorkspaceRunE(cmd *cobra.Command, args []string) error {
 	defer run.Close()
 	ctx = run.WithNamespace(ctx, runCfg.Namespace)
 
+	if buildWorkspaceFrom != "" {
+		_, layer, err := ws.FindLayer(buildWorkspaceFrom)
+		if err != nil {
+			return err
+		}
+		if layer.Opaque {
+			return errdefs.InvalidArgument("snapshot layer '%s' cannot be rebuilt", layer.Name)
+		}
+		// the build cache is skipped for the invalidated layers
+		ws.UpdateLayer(layer)
+	}
+
+	if buildDryRun {
+		return showBuildPlan(ctx, run, ws)
+	}
+
 	// only allow a single build container at a time
 	ctr, err := container.GetContainer(ctx, run, ws)
 	if err != nil && !errors.Is(err, errdefs.ErrNotFound) {
 		return err
 	}
 	if err == nil {
-		if !buildWorkspaceForce && buildWorkspaceUpgrade == "" {
+		if !buildWorkspaceForce && !buildWorkspaceNoCache &&
+			buildWorkspaceUpgrade == "" && buildWorkspaceFrom == "" {
 			return errdefs.AlreadyExists("container", ctr.Name())
 		}
 		err = ctr.Purge(ctx)
@@ -205,20 +345,34 @@ func buildWorkspaceRunE(cmd *cobra.Command, args []string) error {
 			return err
 		}
 	}
+
+	// don't block other commands while building; writing the project fails with a conflict
+	// if another command changed the project in the meantime
+	unlockProject()
 	_, err = buildContainer(ctx, run, ws, -1)
 	if err != nil {
 		return err
 	}
 
+	err = lockProject()
+	if err != nil {
+		return err
+	}
 	return prj.Write()
 }
 
 func init() {
 
 	rootCmd.AddCommand(buildCmd)
+	buildCmd.PersistentFlags().BoolVar(
+		&buildDryRun, "dry-run", false, "Only show the cached layers and the layers to build")
 	buildCmd.AddCommand(buildWorkspaceCmd)
 	buildWorkspaceCmd.Flags().BoolVar(
 		&buildWorkspaceForce, "force", false, "Force a rebuild of the container")
 	buildWorkspaceCmd.Flags().StringVar(
 		&buildWorkspaceUpgrade, "upgrade", "", "Upgrade image, apt, all")
+	buildWorkspaceCmd.Flags().BoolVar(
+		&buildWorkspaceNoCache, "no-cache", false, "Rebuild the layers without the build cache")
+	buildWorkspaceCmd.Flags().StringVar(
+		&buildWorkspaceFrom, "from", "", "Rebuild the workspace starting with this layer")
 }
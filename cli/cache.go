@@ -0,0 +1,149 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/czankel/cne/container"
+	"github.com/czankel/cne/errdefs"
+	"github.com/czankel/cne/project"
+	"github.com/czankel/cne/runtime"
+)
+
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Export and import the build cache",
+	Args:  cobra.MinimumNArgs(1),
+}
+
+var cacheExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export the layer snapshots of a workspace to an archive",
+	Long: `
+Export the layer snapshots of the workspace, including the layers of any base
+workspace, as diffs to their parent snapshots together with their build cache
+labels. Import the archive with 'cache import' on another system to avoid
+rebuilding the layers. The image of the workspace has to be pulled on that
+system before importing the archive.`,
+	Args: cobra.NoArgs,
+	RunE: cacheExportRunE,
+}
+
+var cacheExportWorkspace string
+var cacheExportOutput string
+
+func cacheExportRunE(cmd *cobra.Command, args []string) error {
+
+	runCfg, err := conf.GetRuntime()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	run, err := runtime.Open(ctx, runCfg)
+	if err != nil {
+		return err
+	}
+	defer run.Close()
+	ctx = run.WithNamespace(ctx, runCfg.Namespace)
+
+	prj, err := readProject()
+	if err != nil {
+		return err
+	}
+
+	var ws *project.Workspace
+	if cacheExportWorkspace != "" {
+		ws, err = prj.Workspace(cacheExportWorkspace)
+	} else {
+		ws, err = prj.CurrentWorkspace()
+	}
+	if err != nil {
+		return err
+	}
+
+	img, err := localOriginImage(ctx, run, ws)
+	if err != nil {
+		return err
+	}
+
+	names, err := container.CacheSnapshots(ctx, run, img, ws)
+	if err != nil && errors.Is(err, errdefs.ErrNotFound) {
+		return errdefs.InvalidArgument("workspace '%s' has no built layers", ws.Name)
+	}
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Create(cacheExportOutput)
+	if err != nil {
+		return errdefs.InvalidArgument("failed to create '%s': %v", cacheExportOutput, err)
+	}
+
+	err = run.ExportSnapshots(ctx, names, file)
+	if cerr := file.Close(); err == nil && cerr != nil {
+		err = errdefs.SystemError(cerr, "failed to write '%s'", cacheExportOutput)
+	}
+	if err != nil {
+		os.Remove(cacheExportOutput)
+		return err
+	}
+
+	fmt.Printf("Exported %d snapshots to '%s'\n", len(names), cacheExportOutput)
+	return nil
+}
+
+var cacheImportCmd = &cobra.Command{
+	Use:   "import file",
+	Short: "Import layer snapshots from an archive",
+	Args:  cobra.ExactArgs(1),
+	RunE:  cacheImportRunE,
+}
+
+func cacheImportRunE(cmd *cobra.Command, args []string) error {
+
+	runCfg, err := conf.GetRuntime()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	run, err := runtime.Open(ctx, runCfg)
+	if err != nil {
+		return err
+	}
+	defer run.Close()
+	ctx = run.WithNamespace(ctx, runCfg.Namespace)
+
+	file, err := os.Open(args[0])
+	if err != nil {
+		return errdefs.InvalidArgument("failed to open '%s': %v", args[0], err)
+	}
+	defer file.Close()
+
+	names, err := run.ImportSnapshots(ctx, file)
+	if err != nil && errors.Is(err, errdefs.ErrNotFound) {
+		return errdefs.InvalidArgument("%v: pull the image of the workspace first", err)
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Imported %d snapshots\n", len(names))
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(cacheCmd)
+	cacheCmd.AddCommand(cacheExportCmd)
+	cacheExportCmd.Flags().StringVarP(
+		&cacheExportWorkspace, "workspace", "w", "", "Name of the workspace")
+	cacheExportCmd.Flags().StringVarP(
+		&cacheExportOutput, "output", "o", "", "Name of the archive file")
+	cacheExportCmd.MarkFlagRequired("output")
+	cacheCmd.AddCommand(cacheImportCmd)
+}
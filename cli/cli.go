@@ -62,9 +62,34 @@ func writeConfig(conf *config.Config) error {
 	return conf.WriteUserConfig()
 }
 
-// helper function to load the project
+// prjLock serializes loading, modifying, and writing the project with other cne processes.
+var prjLock *project.Lock
+
+// helper function to acquire the project lock if it isn't held already
+// The lock is held until unlockProject is called or the process exits.
+func lockProject() error {
+
+	if prjLock == nil {
+		lock, err := project.LockProject(projectPath, func() {
+			fmt.Printf("Waiting for another %s command to complete...\n", basename)
+		})
+		if err != nil {
+			return err
+		}
+		prjLock = lock
+	}
+	return nil
+}
+
+// helper function to lock and load the project
+// The lock is held until unlockProject is called or the process exits.
 func loadProject() (*project.Project, error) {
 
+	err := lockProject()
+	if err != nil {
+		return nil, err
+	}
+
 	prj, err := project.Load(projectPath)
 	if err != nil {
 		return nil, err
@@ -73,6 +98,39 @@ func loadProject() (*project.Project, error) {
 	return prj, nil
 }
 
+// helper function to load the project for commands that don't update the project
+// The project is loaded with a shared lock, which is released after loading it.
+func readProject() (*project.Project, error) {
+
+	if prjLock != nil {
+		return loadProject()
+	}
+
+	lock, err := project.LockProjectShared(projectPath, func() {
+		fmt.Printf("Waiting for another %s command to complete...\n", basename)
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer lock.Unlock()
+
+	prj, err := project.Load(projectPath)
+	if err != nil {
+		return nil, err
+	}
+	projectPath = prj.Path
+	return prj, nil
+}
+
+// helper function to release the project lock before long running operations that don't
+// update the project anymore, such as interactive shells.
+func unlockProject() {
+	if prjLock != nil {
+		prjLock.Unlock()
+		prjLock = nil
+	}
+}
+
 var rootCmd = &cobra.Command{
 	SilenceErrors: true,
 	SilenceUsage:  true,
@@ -134,6 +192,12 @@ func initConfig() {
 	if err == nil {
 		projectPath, err = project.GetProjectPath(projectPath)
 	}
+	if err == nil {
+		err = parseOutputFormat(rootOutput)
+	}
+	if err == nil {
+		err = parseProgressMode(rootProgress)
+	}
 	if err == nil {
 		err = conf.Update(config.SystemConfigFile)
 	}
@@ -156,6 +220,10 @@ func init() {
 		&rootCneVersion, "version", false, "Get version information")
 	rootCmd.PersistentFlags().StringVarP(
 		&projectPath, "path", "P", "", "Projet path")
+	rootCmd.PersistentFlags().StringVar(
+		&rootOutput, "output", "", "Output format: json, yaml, or go-template=<template>")
+	rootCmd.PersistentFlags().StringVar(
+		&rootProgress, "progress", "", "Progress output: plain, tty, or json")
 	// Remove the -h help shorthand
 	rootCmd.PersistentFlags().BoolP("help", "", false, "help for cne")
 	rootCmd.AddCommand(rootVersionCmd)
@@ -0,0 +1,83 @@
+package cli
+
+import (
+	"context"
+	"errors"
+
+	"github.com/spf13/cobra"
+
+	"github.com/czankel/cne/container"
+	"github.com/czankel/cne/errdefs"
+	"github.com/czankel/cne/project"
+	"github.com/czankel/cne/runtime"
+)
+
+var commitCmd = &cobra.Command{
+	Use:   "commit layer",
+	Short: "Capture the changes made in the container in a new layer",
+	Long: `
+Capture all changes made interactively in the container of the workspace, for
+example, with 'shell' or 'exec', in a new snapshot layer on top of the existing
+layers. Any processes running in the container are stopped.
+
+Snapshot layers cannot be rebuilt from commands. They keep their snapshot if a
+layer below changes, but the workspace cannot be built until the snapshot layer
+is deleted and committed again. Add the commands to the layer and use
+'update layer --replayable' to convert it to a regular layer.`,
+	Args: cobra.ExactArgs(1),
+	RunE: commitRunE,
+}
+
+var commitWorkspace string
+
+func commitRunE(cmd *cobra.Command, args []string) error {
+
+	runCfg, err := conf.GetRuntime()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	run, err := runtime.Open(ctx, runCfg)
+	if err != nil {
+		return err
+	}
+	defer run.Close()
+	ctx = run.WithNamespace(ctx, runCfg.Namespace)
+
+	prj, err := loadProject()
+	if err != nil {
+		return err
+	}
+
+	var ws *project.Workspace
+	if commitWorkspace != "" {
+		ws, err = prj.Workspace(commitWorkspace)
+	} else {
+		ws, err = prj.CurrentWorkspace()
+	}
+	if err != nil {
+		return err
+	}
+
+	ctr, err := container.GetContainer(ctx, run, ws)
+	if err != nil && errors.Is(err, errdefs.ErrNotFound) {
+		return errdefs.InvalidArgument("workspace '%s' has no active container", ws.Name)
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = container.CommitLayer(ctx, run, ctr, ws, args[0])
+	if err != nil {
+		return err
+	}
+
+	return prj.Write()
+}
+
+func init() {
+	rootCmd.AddCommand(commitCmd)
+	commitCmd.Flags().StringVarP(
+		&commitWorkspace, "workspace", "w", "", "Name of the workspace")
+}
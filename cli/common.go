@@ -31,15 +31,14 @@ func scanLine(line string) []project.Command {
 		if pos != -1 {
 			if pos > 0 {
 				commands = append(commands, project.Command{
-					"",
-					[]string{},
-					[]string{strings.TrimSpace(line[:pos])},
+					Envs: []string{},
+					Args: []string{strings.TrimSpace(line[:pos])},
 				})
 			}
 			line = strings.TrimSpace(line[pos+1:])
 		} else {
 			commands = append(commands,
-				project.Command{"", []string{}, []string{line}})
+				project.Command{Envs: []string{}, Args: []string{line}})
 			break
 		}
 	}
@@ -55,7 +54,7 @@ func readCommands(reader io.Reader) ([]project.Command, error) {
 	for scanner.Scan() {
 		line := strings.TrimSpace(scanner.Text())
 		commands = append(commands,
-			project.Command{"", []string{}, []string{line}})
+			project.Command{Envs: []string{}, Args: []string{line}})
 	}
 	if err := scanner.Err(); err != nil {
 		return nil, errdefs.InvalidArgument("unable to read line: %v", err)
@@ -63,6 +62,94 @@ func readCommands(reader io.Reader) ([]project.Command, error) {
 	return commands, nil
 }
 
+// commandLine returns the environment variables and arguments of the command as a single line
+func commandLine(cmd project.Command) string {
+	return strings.TrimSpace(strings.Join(cmd.Envs, " ") + " " + strings.Join(cmd.Args, " "))
+}
+
+// diffLines compares the old and new lines and returns all lines prefixed by "- " for removed
+// lines, "+ " for added lines, and "  " for unchanged lines.
+func diffLines(oldLines, newLines []string) []string {
+
+	// longest common subsequence table
+	lcs := make([][]int, len(oldLines)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(newLines)+1)
+	}
+	for i := len(oldLines) - 1; i >= 0; i-- {
+		for j := len(newLines) - 1; j >= 0; j-- {
+			if oldLines[i] == newLines[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var diff []string
+	i, j := 0, 0
+	for i < len(oldLines) && j < len(newLines) {
+		if oldLines[i] == newLines[j] {
+			diff = append(diff, "  "+oldLines[i])
+			i++
+			j++
+		} else if lcs[i+1][j] >= lcs[i][j+1] {
+			diff = append(diff, "- "+oldLines[i])
+			i++
+		} else {
+			diff = append(diff, "+ "+newLines[j])
+			j++
+		}
+	}
+	for ; i < len(oldLines); i++ {
+		diff = append(diff, "- "+oldLines[i])
+	}
+	for ; j < len(newLines); j++ {
+		diff = append(diff, "+ "+newLines[j])
+	}
+	return diff
+}
+
+// parseSelection parses a comma separated list of indices and ranges, such as "1,3-5", for a
+// list of count entries starting at index 1 and returns the sorted 0-based indices. Use "all"
+// to select all entries.
+func parseSelection(sel string, count int) ([]int, error) {
+
+	sel = strings.TrimSpace(sel)
+	if sel == "all" {
+		sel = "1-" + strconv.Itoa(count)
+	}
+
+	selected := make(map[int]bool)
+	for _, s := range strings.Split(sel, ",") {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		from, to := s, s
+		if pos := strings.Index(s, "-"); pos > 0 {
+			from, to = s[:pos], s[pos+1:]
+		}
+		first, err1 := strconv.Atoi(strings.TrimSpace(from))
+		last, err2 := strconv.Atoi(strings.TrimSpace(to))
+		if err1 != nil || err2 != nil || first < 1 || last > count || first > last {
+			return nil, errdefs.InvalidArgument("invalid selection: '%s'", s)
+		}
+		for i := first; i <= last; i++ {
+			selected[i-1] = true
+		}
+	}
+
+	var indices []int
+	for i := range selected {
+		indices = append(indices, i)
+	}
+	sort.Ints(indices)
+	return indices, nil
+}
+
 // sizeToSIString converts the provide integer value to a SI size string from the 10^3x exponent
 func sizeToSIString(sz int64) string {
 	const unit = 1000
@@ -198,6 +285,11 @@ func printValueElem(w *tabwriter.Writer, prefix string, elem reflect.Value, flat
 //	<type>: prefix, value
 func printValue(fieldHdr string, valueHdr string, prefix string, value interface{}) {
 
+	if outputFormat != outputText {
+		printOutput(value, false)
+		return
+	}
+
 	w := new(tabwriter.Writer)
 	w.Init(os.Stdout, 8, 0, 1, ' ', 0)
 	defer w.Flush()
@@ -224,6 +316,11 @@ func printList(list interface{}, withIndex bool) {
 		panic("provided argument must be of the type: slice or map of structures")
 	}
 
+	if outputFormat != outputText {
+		printOutput(list, true)
+		return
+	}
+
 	w := new(tabwriter.Writer)
 	w.Init(os.Stdout, 8, 0, 1, ' ', 0)
 	defer w.Flush()
@@ -295,8 +392,11 @@ func printListRow(w *tabwriter.Writer, i int, item reflect.Value, isPtr bool, hd
 				s = s[:len(s)-2]
 			}
 			fmt.Fprintf(w, format, s)
-		} else {
-			if val.CanInterface() {
+		} else if val.CanInterface() {
+			// text output shows times as their age
+			if t, ok := val.Interface().(time.Time); ok {
+				fmt.Fprintf(w, format, timeToAgoString(t))
+			} else {
 				fmt.Fprintf(w, format, val.Interface())
 			}
 		}
@@ -305,10 +405,23 @@ func printListRow(w *tabwriter.Writer, i int, item reflect.Value, isPtr bool, hd
 	fmt.Fprintf(w, "\n")
 }
 
-// showProgress displays the progress of sequential or parallel jobs
-// Use this as a callback function in calls that provide a progress feedback
+// showProgress displays the progress of sequential or parallel jobs in the selected progress
+// mode. Use this as a callback function in calls that provide a progress feedback
 func showProgress(progress <-chan []runtime.ProgressStatus) {
 
+	switch progressMode {
+	case progressPlain:
+		showProgressPlain(progress)
+	case progressJSON:
+		showProgressJSON(progress)
+	default:
+		showProgressTTY(progress)
+	}
+}
+
+// showProgressTTY displays the progress updating the status lines using ANSI escape sequences.
+func showProgressTTY(progress <-chan []runtime.ProgressStatus) {
+
 	lines := 0
 	ticks := 0
 
@@ -374,11 +487,12 @@ func showProgress(progress <-chan []runtime.ProgressStatus) {
 				// runtime.StatusUnknown:
 				// runtime.StatusPending:
 				// runtime.StatusCached:
+				// runtime.StatusSkipped:
 				// runtime.StatusComplete:
 				// runtime.Error
 
 				fmt.Fprintf(w, "[%s] %s\n",
-					ref[:reflen], strings.Title(status.Status))
+					ref[:reflen], statusTitle(status.Status))
 			}
 		}
 
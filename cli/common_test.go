@@ -4,8 +4,20 @@ import (
 	"testing"
 
 	"bytes"
+	"context"
+	"errors"
 	"io"
 	"os"
+	"strings"
+	"time"
+
+	digest "github.com/opencontainers/go-digest"
+	"github.com/spf13/cobra"
+
+	"github.com/czankel/cne/config"
+	"github.com/czankel/cne/errdefs"
+	"github.com/czankel/cne/project"
+	"github.com/czankel/cne/runtime"
 )
 
 // compareString compares the provided strings and returns -1 if they match, or the position
@@ -291,3 +303,538 @@ func TestCliScanArgs(t *testing.T) {
 	testCmds = [][]string{{"cmd1 arg11"}, {"cmd2 arg21"}}
 	compareCommands(t, "multi line, multi delims", testLine, testCmds)
 }
+
+// TestDiffLines tests the line-based diff
+func TestDiffLines(t *testing.T) {
+
+	oldLines := []string{"a", "b", "c", "d"}
+	newLines := []string{"a", "c", "x", "d", "e"}
+	expected := []string{"  a", "- b", "  c", "+ x", "  d", "+ e"}
+
+	diff := diffLines(oldLines, newLines)
+	if len(diff) != len(expected) {
+		t.Fatalf("Invalid diff length %d, expected %d: %v", len(diff), len(expected), diff)
+	}
+	for i := range diff {
+		if diff[i] != expected[i] {
+			t.Errorf("Diff line %d is '%s', expected '%s'", i, diff[i], expected[i])
+		}
+	}
+}
+
+// TestParseSelection tests parseSelection for single entries, ranges, and invalid selections
+func TestParseSelection(t *testing.T) {
+
+	type testcase struct {
+		sel string
+		res []int
+	}
+	testcases := []testcase{
+		{"", nil},
+		{"1", []int{0}},
+		{"3,1", []int{0, 2}},
+		{"2-4", []int{1, 2, 3}},
+		{" 1 , 2-3, 3", []int{0, 1, 2}},
+		{"all", []int{0, 1, 2, 3}},
+		{"0", []int{-1}},
+		{"5", []int{-1}},
+		{"3-2", []int{-1}},
+		{"a", []int{-1}},
+		{"-1", []int{-1}},
+	}
+
+	for _, tc := range testcases {
+		res, err := parseSelection(tc.sel, 4)
+		if len(tc.res) == 1 && tc.res[0] == -1 {
+			if err == nil {
+				t.Errorf("Selection '%s' should have failed", tc.sel)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Selection '%s' failed: %v", tc.sel, err)
+			continue
+		}
+		if len(res) != len(tc.res) {
+			t.Errorf("Selection '%s' should be %v: %v", tc.sel, tc.res, res)
+			continue
+		}
+		for i := range res {
+			if res[i] != tc.res[i] {
+				t.Errorf("Selection '%s' should be %v: %v", tc.sel, tc.res, res)
+				break
+			}
+		}
+	}
+}
+
+func TestPrintOutput(t *testing.T) {
+
+	type testStruct struct {
+		Name   string
+		Hidden string `output:"-"`
+		Values []int
+	}
+	testList := []testStruct{
+		{Name: "first", Hidden: "hidden", Values: []int{1, 2}},
+		{Name: "second"},
+	}
+
+	defer parseOutputFormat("")
+
+	if err := parseOutputFormat("json"); err != nil {
+		t.Fatalf("Failed to set output format: %v", err)
+	}
+	expected := `[
+  {
+    "Name": "first",
+    "Values": [
+      1,
+      2
+    ]
+  },
+  {
+    "Name": "second",
+    "Values": []
+  }
+]
+`
+	pos, str := compareFuncOutput(func() { printList(testList, false) }, expected)
+	if pos != -1 {
+		t.Errorf("JSON output mismatch at %d:\n%s", pos, str)
+	}
+
+	if err := parseOutputFormat("go-template={{.Name}}"); err != nil {
+		t.Fatalf("Failed to set output format: %v", err)
+	}
+	pos, str = compareFuncOutput(func() { printList(testList, false) }, "first\nsecond\n")
+	if pos != -1 {
+		t.Errorf("Template output mismatch at %d:\n%s", pos, str)
+	}
+
+	for _, format := range []string{"xml", "json=", "go-template", "go-template={{"} {
+		if err := parseOutputFormat(format); err == nil {
+			t.Errorf("Output format '%s' should have failed", format)
+		}
+	}
+}
+
+func TestOriginStatus(t *testing.T) {
+
+	ws := &project.Workspace{Name: "dev"}
+	tests := []struct {
+		origin string
+		digest string
+		latest string
+		status string
+	}{
+		{"", "", "", outdatedNoImage},
+		{"image@sha256:1234", "", "", outdatedFixed},
+		{"image:latest", "", "sha256:1234", outdatedUnpinned},
+		{"image:latest", "sha256:1234", "sha256:1234", outdatedUpToDate},
+		{"image:latest", "sha256:1234", "sha256:5678", outdatedOutdated},
+	}
+	for _, test := range tests {
+		ws.Environment.Origin = test.origin
+		ws.Environment.OriginDigest = test.digest
+		if status := originStatus(ws, test.latest); status != test.status {
+			t.Errorf("Status of '%s' should be %s: %s", test.origin, test.status, status)
+		}
+	}
+
+	if d := shortDigest("sha256:0123456789abcdef"); d != "01234567" {
+		t.Errorf("Short digest mismatch: %s", d)
+	}
+}
+
+func TestPlatformEmulator(t *testing.T) {
+
+	tests := []struct {
+		host     string
+		platform string
+		emulator string
+	}{
+		{"amd64", "", ""},
+		{"amd64", "linux/amd64", ""},
+		{"amd64", "linux/386", ""},
+		{"amd64", "linux/arm64", "qemu-aarch64"},
+		{"amd64", "linux/arm/v7", "qemu-arm"},
+		{"arm64", "linux/arm/v7", ""},
+		{"arm64", "linux/amd64", "qemu-x86_64"},
+		{"arm64", "linux/loong64", "qemu-loong64"},
+	}
+	for _, test := range tests {
+		emulator := platformEmulator(test.host, test.platform)
+		if emulator != test.emulator {
+			t.Errorf("Emulator for %s on %s should be '%s': '%s'",
+				test.platform, test.host, test.emulator, emulator)
+		}
+	}
+}
+
+// TestRecordCommand tests that the recorded command lines are replayed in a single shell
+func TestRecordCommand(t *testing.T) {
+
+	cmd := recordCommand([]string{"cd /tmp", "export FOO=bar", "echo $FOO > foo"})
+	if len(cmd.Args) != 3 || cmd.Args[0] != recordShell || cmd.Args[1] != "-c" {
+		t.Fatalf("Command should run the lines in %s: %v", recordShell, cmd.Args)
+	}
+	if cmd.Args[2] != "cd /tmp\nexport FOO=bar\necho $FOO > foo" {
+		t.Errorf("Command should include all lines in order: %q", cmd.Args[2])
+	}
+}
+
+// TestWorkspaceOwner tests attributing layer snapshots to projects by the workspace label
+func TestWorkspaceOwner(t *testing.T) {
+
+	prjUUID := "0f8fad5b-d9cb-469f-a165-70867728950e"
+	prjNames := map[string]string{prjUUID: "cne"}
+
+	type testcase struct {
+		label string
+		prj   string
+		ws    string
+		ok    bool
+	}
+	testcases := []testcase{
+		{prjUUID + "/dev", "cne", "dev", true},
+		{"7c9e6679-7425-40de-944b-e07fc1f90ae7/test", "7c9e6679", "test", true},
+		{"", "", "", false},
+		{prjUUID, "", "", false},
+		{"invalid/dev", "", "", false},
+	}
+
+	for _, tc := range testcases {
+		prj, ws, ok := workspaceOwner(tc.label, prjNames)
+		if prj != tc.prj || ws != tc.ws || ok != tc.ok {
+			t.Errorf("Label '%s' should be owned by '%s/%s' (%t): '%s/%s' (%t)",
+				tc.label, tc.prj, tc.ws, tc.ok, prj, ws, ok)
+		}
+	}
+}
+
+type testSnapshot struct {
+	name      string
+	parent    string
+	createdAt time.Time
+}
+
+func (s *testSnapshot) Name() string              { return s.name }
+func (s *testSnapshot) Parent() string            { return s.parent }
+func (s *testSnapshot) CreatedAt() time.Time      { return s.createdAt }
+func (s *testSnapshot) Size() int64               { return 0 }
+func (s *testSnapshot) Inodes() int64             { return 0 }
+func (s *testSnapshot) Labels() map[string]string { return nil }
+
+// TestSnapshotsLastUsed tests that snapshots are used when snapshots are built on top of them
+func TestSnapshotsLastUsed(t *testing.T) {
+
+	now := time.Now()
+	snaps := []runtime.Snapshot{
+		&testSnapshot{name: "img", createdAt: now.Add(-72 * time.Hour)},
+		&testSnapshot{name: "l0", parent: "img", createdAt: now.Add(-48 * time.Hour)},
+		&testSnapshot{name: "l1", parent: "l0", createdAt: now.Add(-time.Hour)},
+		&testSnapshot{name: "other", createdAt: now.Add(-96 * time.Hour)},
+	}
+	snapMap := make(map[string]runtime.Snapshot)
+	for _, s := range snaps {
+		snapMap[s.Name()] = s
+	}
+
+	lastUsed := snapshotsLastUsed(snaps, snapMap)
+	for _, name := range []string{"img", "l0", "l1"} {
+		if !lastUsed[name].Equal(now.Add(-time.Hour)) {
+			t.Errorf("Snapshot '%s' should have been used an hour ago: %v", name, lastUsed[name])
+		}
+	}
+	if !lastUsed["other"].Equal(now.Add(-96 * time.Hour)) {
+		t.Errorf("Snapshot 'other' should only have been used when created")
+	}
+}
+
+// TestPrintListTime tests that times are shown relative in text output and as RFC3339 in
+// structured output
+func TestPrintListTime(t *testing.T) {
+
+	createdAt := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	testList := []struct {
+		Name      string
+		CreatedAt time.Time
+	}{{Name: "first", CreatedAt: createdAt}}
+
+	defer parseOutputFormat("")
+
+	expected := "NAME    CREATEDAT\nfirst   " + timeToAgoString(createdAt) + "\n"
+	pos, str := compareFuncOutput(func() { printList(testList, false) }, expected)
+	if pos != -1 {
+		t.Errorf("Text output mismatch at %d:\n%s", pos, str)
+	}
+
+	if err := parseOutputFormat("json"); err != nil {
+		t.Fatalf("Failed to set output format: %v", err)
+	}
+	expected = "[\n  {\n    \"CreatedAt\": \"2020-01-02T03:04:05Z\",\n    \"Name\": \"first\"\n  }\n]\n"
+	pos, str = compareFuncOutput(func() { printList(testList, false) }, expected)
+	if pos != -1 {
+		t.Errorf("JSON output mismatch at %d:\n%s", pos, str)
+	}
+}
+
+// TestImageLoadSaveFlags tests the arguments and flags of the image load and save commands
+func TestImageLoadSaveFlags(t *testing.T) {
+
+	type testcase struct {
+		cmd   *cobra.Command
+		flag  string
+		short string
+		args  []string
+		value *string
+		plat  *string
+	}
+	testcases := []testcase{
+		{imageLoadCmd, "input", "i", []string{}, &imageLoadInput, &imageLoadPlatform},
+		{imageSaveCmd, "file", "o", []string{"ubuntu"}, &imageSaveFile, &imageSavePlatform},
+	}
+
+	for _, tc := range testcases {
+		if err := tc.cmd.ValidateArgs(tc.args); err != nil {
+			t.Errorf("Command '%s' should accept %v: %v", tc.cmd.Name(), tc.args, err)
+		}
+		if err := tc.cmd.ValidateArgs(append(tc.args, "extra")); err == nil {
+			t.Errorf("Command '%s' should not accept additional arguments", tc.cmd.Name())
+		}
+
+		f := tc.cmd.Flags().Lookup(tc.flag)
+		if f == nil || f.Shorthand != tc.short {
+			t.Fatalf("Command '%s' should have the flag -%s, --%s",
+				tc.cmd.Name(), tc.short, tc.flag)
+		}
+		if req := f.Annotations[cobra.BashCompOneRequiredFlag]; len(req) != 1 || req[0] != "true" {
+			t.Errorf("Flag --%s of '%s' should be required", tc.flag, tc.cmd.Name())
+		}
+
+		err := tc.cmd.ParseFlags([]string{"-" + tc.short, "images.tar"})
+		if err != nil || *tc.value != "images.tar" {
+			t.Errorf("Flag -%s of '%s' should have been parsed: %v",
+				tc.short, tc.cmd.Name(), err)
+		}
+		*tc.value = ""
+		f.Changed = false
+
+		err = tc.cmd.ParseFlags([]string{"--platform", "linux/arm64"})
+		if err != nil || *tc.plat != "linux/arm64" {
+			t.Errorf("Flag --platform of '%s' should have been parsed: %v", tc.cmd.Name(), err)
+		}
+		*tc.plat = ""
+		tc.cmd.Flags().Lookup("platform").Changed = false
+
+		// the global --output flag selects the output format
+		if tc.cmd.LocalFlags().Lookup("output") != nil {
+			t.Errorf("Command '%s' should not shadow the --output flag", tc.cmd.Name())
+		}
+	}
+}
+
+type testImage struct {
+	runtime.Image
+	name       string
+	repoDigest digest.Digest
+}
+
+func (img *testImage) Name() string              { return img.name }
+func (img *testImage) RepoDigest() digest.Digest { return img.repoDigest }
+
+// testRuntime is a runtime with local images that resolves and pulls images with the digest
+// of the remote image, or with the digest of the reference for images pulled by digest.
+type testRuntime struct {
+	runtime.Runtime
+	images     map[string]*testImage
+	remote     digest.Digest
+	resolveErr error
+	pulled     []string
+}
+
+func (run *testRuntime) SetRegistries(regs map[string]*config.Registry) {}
+func (run *testRuntime) SetCredentials(creds runtime.Credentials)       {}
+
+func (run *testRuntime) GetImage(ctx context.Context,
+	name, platform string) (runtime.Image, error) {
+	if img, ok := run.images[name]; ok {
+		return img, nil
+	}
+	return nil, errdefs.NotFound("image", name)
+}
+
+func (run *testRuntime) ResolveImage(ctx context.Context, name string) (digest.Digest, error) {
+	return run.remote, run.resolveErr
+}
+
+func (run *testRuntime) PullImage(ctx context.Context, name, platform string,
+	progress chan<- []runtime.ProgressStatus) (runtime.Image, error) {
+	run.pulled = append(run.pulled, name)
+	if _, dgst, ok := strings.Cut(name, "@"); ok {
+		return &testImage{name: name, repoDigest: digest.Digest(dgst)}, nil
+	}
+	return &testImage{name: name, repoDigest: run.remote}, nil
+}
+
+// ImportImage returns an image named by the content of the archive and the platform.
+func (run *testRuntime) ImportImage(ctx context.Context,
+	r io.Reader, platform string) ([]runtime.Image, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return []runtime.Image{&testImage{name: string(data) + "/" + platform}}, nil
+}
+
+// ExportImage writes the name of the image to the archive.
+func (run *testRuntime) ExportImage(ctx context.Context,
+	name, platform string, w io.Writer) error {
+	if _, ok := run.images[name]; !ok {
+		return errdefs.NotFound("image", name)
+	}
+	_, err := w.Write([]byte(name))
+	return err
+}
+
+// TestImageSaveLoad tests saving an image to an archive file and loading it again
+func TestImageSaveLoad(t *testing.T) {
+
+	ctx := context.Background()
+	dir := t.TempDir()
+	path := dir + "/image.tar"
+	run := &testRuntime{images: map[string]*testImage{"ubuntu": {name: "ubuntu"}}}
+
+	err := saveImage(ctx, run, "ubuntu", "linux/arm64", path)
+	if err != nil {
+		t.Fatalf("Failed to save image: %v", err)
+	}
+	imgs, err := loadImages(ctx, run, path, "linux/arm64")
+	if err != nil {
+		t.Fatalf("Failed to load image: %v", err)
+	}
+	if len(imgs) != 1 || imgs[0].Name() != "ubuntu/linux/arm64" {
+		t.Errorf("Loaded images mismatch: %v", imgs)
+	}
+
+	missing := dir + "/missing.tar"
+	err = saveImage(ctx, run, "missing", "", missing)
+	if !errors.Is(err, errdefs.ErrNotFound) {
+		t.Errorf("Saving a missing image should have failed: %v", err)
+	}
+	if _, err := os.Stat(missing); !os.IsNotExist(err) {
+		t.Errorf("Archive of a failed save should have been removed")
+	}
+
+	_, err = loadImages(ctx, run, missing, "")
+	if !errors.Is(err, errdefs.ErrInvalidArgument) || !strings.Contains(err.Error(), missing) {
+		t.Errorf("Loading a missing archive should report the path: %v", err)
+	}
+}
+
+// TestPullOriginImage tests the update strategies when getting the origin image of a workspace
+func TestPullOriginImage(t *testing.T) {
+
+	if conf == nil {
+		conf = config.NewDefault()
+	}
+
+	oldDigest := digest.FromString("old")
+	newDigest := digest.FromString("new")
+	localImages := map[string]*testImage{
+		"ubuntu:22.04":                       {name: "ubuntu:22.04", repoDigest: oldDigest},
+		"ubuntu:22.04@" + oldDigest.String(): {name: "ubuntu:22.04", repoDigest: oldDigest},
+	}
+
+	tests := []struct {
+		name       string
+		update     string
+		pinned     digest.Digest
+		local      bool
+		resolveErr error
+		pulled     []string
+		digest     digest.Digest
+	}{
+		{
+			name:   "auto with updated image",
+			update: project.UpdateAuto,
+			pinned: oldDigest,
+			local:  true,
+			pulled: []string{"docker.io/library/ubuntu:22.04"},
+			digest: newDigest,
+		},
+		{
+			name:   "auto without pinned digest",
+			update: project.UpdateAuto,
+			pulled: []string{"docker.io/library/ubuntu:22.04"},
+			digest: newDigest,
+		},
+		{
+			name:       "auto with unreachable registry",
+			update:     project.UpdateAuto,
+			pinned:     oldDigest,
+			local:      true,
+			resolveErr: runtime.Errorf("registry unreachable"),
+			digest:     oldDigest,
+		},
+		{
+			name:   "manual with local image",
+			update: project.UpdateManual,
+			pinned: oldDigest,
+			local:  true,
+			digest: oldDigest,
+		},
+		{
+			name:   "manual without local image",
+			update: project.UpdateManual,
+			pinned: oldDigest,
+			pulled: []string{"docker.io/library/ubuntu@" + oldDigest.String()},
+			digest: oldDigest,
+		},
+		{
+			name:   "never without pinned digest",
+			update: project.UpdateNever,
+			pulled: []string{"docker.io/library/ubuntu:22.04"},
+			digest: newDigest,
+		},
+		{
+			name:   "never with local image",
+			update: project.UpdateNever,
+			pinned: oldDigest,
+			local:  true,
+			digest: oldDigest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			run := &testRuntime{remote: newDigest, resolveErr: tt.resolveErr}
+			if tt.local {
+				run.images = localImages
+			}
+			ws := &project.Workspace{
+				Environment: project.Environment{
+					Origin:       "ubuntu:22.04",
+					OriginDigest: tt.pinned.String(),
+					Update:       tt.update,
+				},
+			}
+
+			img, err := pullOriginImage(context.Background(), run, ws)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if img.RepoDigest() != tt.digest {
+				t.Errorf("Expected image %s, got %s", tt.digest, img.RepoDigest())
+			}
+			if ws.Environment.OriginDigest != tt.digest.String() {
+				t.Errorf("Workspace should be pinned to %s, got '%s'",
+					tt.digest, ws.Environment.OriginDigest)
+			}
+			if strings.Join(run.pulled, ",") != strings.Join(tt.pulled, ",") {
+				t.Errorf("Expected pulled images %v, got %v", tt.pulled, run.pulled)
+			}
+		})
+	}
+}
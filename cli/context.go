@@ -38,7 +38,7 @@ func contextRunE(cmd *cobra.Command, args []string) error {
 			return errdefs.NotFound("context", name)
 		}
 
-		_, err := loadProject()
+		_, err := readProject()
 		if configProject && err != nil {
 			return err
 		}
@@ -0,0 +1,76 @@
+package cli
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/czankel/cne/errdefs"
+	"github.com/czankel/cne/project"
+)
+
+var copyCmd = &cobra.Command{
+	Use:     "copy",
+	Short:   "Copy resources",
+	Aliases: []string{"cp"},
+	Args:    cobra.MinimumNArgs(1),
+}
+
+var copyLayerCmd = &cobra.Command{
+	Use:     "layer name",
+	Short:   "Copy a layer to another workspace",
+	Aliases: []string{"l"},
+	Args:    cobra.ExactArgs(1),
+	RunE:    copyLayerRunE,
+}
+
+var copyLayerWorkspace string
+var copyLayerTo string
+var copyLayerInsert string
+
+func copyLayerRunE(cmd *cobra.Command, args []string) error {
+
+	prj, err := loadProject()
+	if err != nil {
+		return err
+	}
+
+	var from *project.Workspace
+	if copyLayerWorkspace != "" {
+		from, err = prj.Workspace(copyLayerWorkspace)
+	} else {
+		from, err = prj.CurrentWorkspace()
+	}
+	if err != nil {
+		return err
+	}
+
+	if copyLayerTo == "" {
+		return errdefs.InvalidArgument("no destination workspace provided")
+	}
+	to, err := prj.Workspace(copyLayerTo)
+	if err != nil {
+		return err
+	}
+
+	_, layer, err := from.FindLayer(args[0])
+	if err != nil {
+		return err
+	}
+
+	_, _, err = to.CopyLayer(layer, copyLayerInsert)
+	if err != nil {
+		return err
+	}
+
+	return prj.Write()
+}
+
+func init() {
+	rootCmd.AddCommand(copyCmd)
+	copyCmd.AddCommand(copyLayerCmd)
+	copyLayerCmd.Flags().StringVarP(
+		&copyLayerWorkspace, "workspace", "w", "", "Copy the layer from this workspace")
+	copyLayerCmd.Flags().StringVar(
+		&copyLayerTo, "to", "", "Copy the layer to this workspace")
+	copyLayerCmd.Flags().StringVar(
+		&copyLayerInsert, "insert", "", "Insert before this layer")
+}
@@ -56,7 +56,7 @@ func updateContainer(ws *project.Workspace, layerIdx int) error {
 	return err
 }
 
-func initWorkspace(prj *project.Project, wsName, insert, imgName string) error {
+func initWorkspace(prj *project.Project, wsName, insert, imgName, platform string) error {
 
 	ws, err := prj.CreateWorkspace(wsName, "", insert)
 	if err != nil {
@@ -82,7 +82,11 @@ func initWorkspace(prj *project.Project, wsName, insert, imgName string) error {
 			return err
 		}
 
-		img, err := pullImage(ctx, run, imgName)
+		// use a local image, for example, loaded with 'image load', before pulling the image
+		img, err := run.GetImage(ctx, imgName, platform)
+		if err != nil && errors.Is(err, errdefs.ErrNotFound) {
+			img, err = pullImage(ctx, run, imgName, platform)
+		}
 		if err != nil {
 			return err
 		}
@@ -110,7 +114,10 @@ func initWorkspace(prj *project.Project, wsName, insert, imgName string) error {
 			return err
 		}
 
-		prj.UpdateWorkspace(ws, imgName)
+		prj.UpdateWorkspace(ws, imgName, img.RepoDigest().String())
+		if platform != "" {
+			ws.Environment.Platform = img.Platform()
+		}
 
 		err = support.SetupWorkspace(ctx, ws, img)
 		if err != nil {
@@ -289,6 +296,9 @@ var createRegistryCmd = &cobra.Command{
 
 var createRegistryDomain string
 var createRegistryRepoName string
+var createRegistryMirrors []string
+var createRegistryInsecure bool
+var createRegistryCAFile string
 
 func createRegistryRunE(cmd *cobra.Command, args []string) error {
 
@@ -308,6 +318,9 @@ func createRegistryRunE(cmd *cobra.Command, args []string) error {
 	if createRegistryRepoName != "" {
 		confReg.RepoName = createRegistryRepoName
 	}
+	confReg.Mirrors = createRegistryMirrors
+	confReg.Insecure = createRegistryInsecure
+	confReg.CAFile = createRegistryCAFile
 
 	err = writeConfig(tempConf)
 	if err != nil {
@@ -367,7 +380,10 @@ var createWorkspaceCmd = &cobra.Command{
 }
 
 var createWorkspaceImage string
+var createWorkspacePlatform string
 var createWorkspaceInsert string
+var createWorkspaceBase string
+var createWorkspaceFrom string
 
 func createWorkspaceRunE(cmd *cobra.Command, args []string) error {
 
@@ -381,7 +397,44 @@ func createWorkspaceRunE(cmd *cobra.Command, args []string) error {
 		wsName = args[0]
 	}
 
-	return initWorkspace(prj, wsName, createWorkspaceImage, createWorkspaceInsert)
+	if createWorkspacePlatform != "" && createWorkspaceImage == "" {
+		return errdefs.InvalidArgument("platform requires an image")
+	}
+
+	if createWorkspaceFrom != "" {
+		if createWorkspaceImage != "" || createWorkspaceBase != "" {
+			return errdefs.InvalidArgument("cloned workspace cannot have an image or base")
+		}
+		from, err := prj.Workspace(createWorkspaceFrom)
+		if err != nil {
+			return err
+		}
+		ws, err := prj.CloneWorkspace(from, wsName, createWorkspaceInsert)
+		if err != nil {
+			return err
+		}
+		prj.CurrentWorkspaceName = ws.Name
+		return prj.Write()
+	}
+
+	if createWorkspaceBase != "" {
+		if createWorkspaceImage != "" {
+			return errdefs.InvalidArgument("workspace with a base cannot have an image")
+		}
+		ws, err := prj.CreateWorkspace(wsName, "", createWorkspaceInsert)
+		if err != nil {
+			return err
+		}
+		err = prj.SetWorkspaceBase(ws, createWorkspaceBase)
+		if err != nil {
+			return err
+		}
+		prj.CurrentWorkspaceName = ws.Name
+		return prj.Write()
+	}
+
+	return initWorkspace(prj, wsName, createWorkspaceInsert,
+		createWorkspaceImage, createWorkspacePlatform)
 }
 
 func init() {
@@ -412,6 +465,13 @@ func init() {
 		&createRegistryDomain, "domain", "", "Registry domain")
 	createRegistryCmd.Flags().StringVar(
 		&createRegistryRepoName, "reponame", "", "Registry repooname")
+	createRegistryCmd.Flags().StringSliceVar(
+		&createRegistryMirrors, "mirror", []string{}, "Registry mirror endpoints")
+	createRegistryCmd.Flags().BoolVar(
+		&createRegistryInsecure, "insecure", false,
+		"Skip TLS verification and allow plain HTTP for the registry")
+	createRegistryCmd.Flags().StringVar(
+		&createRegistryCAFile, "ca-file", "", "CA bundle for the registry")
 	createRegistryCmd.Flags().BoolVarP(
 		&configSystem, "system", "", false, "System configuration")
 	createRegistryCmd.Flags().BoolVarP(
@@ -432,6 +492,13 @@ func init() {
 	createCmd.AddCommand(createWorkspaceCmd)
 	createWorkspaceCmd.Flags().StringVar(
 		&createWorkspaceImage, "image", "", "Base image for the workspace")
+	createWorkspaceCmd.Flags().StringVar(
+		&createWorkspacePlatform, "platform", "",
+		"Platform of the base image, for example, linux/arm64")
 	createWorkspaceCmd.Flags().StringVar(
 		&createWorkspaceInsert, "insert", "", "Insert before this workspace")
+	createWorkspaceCmd.Flags().StringVar(
+		&createWorkspaceBase, "base", "", "Inherit the layers of this workspace")
+	createWorkspaceCmd.Flags().StringVar(
+		&createWorkspaceFrom, "from", "", "Clone this workspace")
 }
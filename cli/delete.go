@@ -160,10 +160,37 @@ func deleteLayerRunE(cmd *cobra.Command, args []string) error {
 }
 
 var deleteContainerCmd = &cobra.Command{
-	Use:   "container name",
+	Use:   "container name|[project/]workspace",
 	Short: "delete container",
-	Args:  cobra.ExactArgs(1),
-	RunE:  deleteContainerRunE,
+	Long: `
+Delete the container with the provided name or of the workspace. Containers of
+other projects can be deleted with the option --all using the project and
+workspace name in the format project/workspace.`,
+	Args: cobra.ExactArgs(1),
+	RunE: deleteContainerRunE,
+}
+
+var deleteContainerAll bool
+
+// matchContainer checks if the container has the provided name or belongs to the workspace
+// in the format "[project/]workspace". The project name can be omitted for containers of the
+// current project.
+func matchContainer(prj *project.Project, ctr runtime.Container, name string) bool {
+
+	if ctr.Name() == name {
+		return true
+	}
+
+	labels := ctr.Labels()
+	prjName := labels[runtime.ProjectNameLabel]
+	wsName := labels[runtime.WorkspaceNameLabel]
+	if wsName == "" {
+		return false
+	}
+	if prjName+"/"+wsName == name {
+		return true
+	}
+	return prj != nil && prjName == prj.Name && wsName == name
 }
 
 func deleteContainerRunE(cmd *cobra.Command, args []string) error {
@@ -179,25 +206,39 @@ func deleteContainerRunE(cmd *cobra.Command, args []string) error {
 		return err
 	}
 	defer run.Close()
+	ctx = run.WithNamespace(ctx, runCfg.Namespace)
 
-	prj, err := loadProject()
-	if err != nil {
+	prj, err := readProject()
+	if err != nil && (!deleteContainerAll || !errors.Is(err, errdefs.ErrNotFound)) {
 		return err
 	}
 
-	// delete all containers that match the domain+id
-	ctrs, err := container.Containers(ctx, run, prj, &user)
+	// containers of all projects with --all
+	var filter *project.Project
+	if !deleteContainerAll {
+		filter = prj
+	}
+	ctrs, err := container.Containers(ctx, run, filter, &user)
 	if err != nil {
 		return err
 	}
+
+	var match runtime.Container
 	for _, c := range ctrs {
-		if c.Name() == args[0] {
-			c.Purge(ctx)
-			break
+		if !matchContainer(prj, c, args[0]) {
+			continue
+		}
+		if match != nil {
+			return errdefs.InvalidArgument(
+				"container name '%s' is ambiguous, use the container name", args[0])
 		}
+		match = c
+	}
+	if match == nil {
+		return errdefs.NotFound("container", args[0])
 	}
 
-	return nil
+	return match.Purge(ctx)
 }
 
 var deleteCommandCmd = &cobra.Command{
@@ -349,6 +390,8 @@ func init() {
 	deleteConfigCmd.AddCommand(deleteConfigRuntimeCmd)
 
 	deleteCmd.AddCommand(deleteContainerCmd)
+	deleteContainerCmd.Flags().BoolVarP(
+		&deleteContainerAll, "all", "A", false, "Delete containers of all projects")
 	deleteCmd.AddCommand(deleteImageCmd)
 	deleteCmd.AddCommand(deleteLayerCmd)
 
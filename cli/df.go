@@ -0,0 +1,317 @@
+package cli
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/opencontainers/image-spec/identity"
+	"github.com/spf13/cobra"
+
+	"github.com/czankel/cne/container"
+	"github.com/czankel/cne/errdefs"
+	"github.com/czankel/cne/project"
+	"github.com/czankel/cne/runtime"
+)
+
+var dfCmd = &cobra.Command{
+	Use:   "df",
+	Short: "Show the disk usage of images, containers, and snapshots",
+	Long: `
+Show the disk space used by images, containers, and snapshots and attribute it
+to the projects and workspaces that use them. Projects of containers created by
+older versions are shown by their project ID.
+
+Containers show the size of the changes in the active container and the size
+of the layer snapshots the container is based on. Cached layers are layer
+snapshots built for a project that aren't used by any container, and
+unreferenced snapshots aren't used by any container or project. Both can be
+removed, but cached layers would have to be rebuilt.`,
+	Args: cobra.NoArgs,
+	RunE: dfRunE,
+}
+
+// diskUsage describes the disk usage of a type of resource of a project or workspace.
+type diskUsage struct {
+	Type        string
+	Project     string
+	Workspace   string
+	Count       int
+	Size        int64
+	Reclaimable int64
+}
+
+// diskUsageList accumulates the disk usage by type, project, and workspace in the order the
+// entries were first added.
+type diskUsageList []diskUsage
+
+func (l *diskUsageList) add(typ, prj, ws string, size int64, reclaimable bool) {
+
+	i := 0
+	for ; i < len(*l); i++ {
+		u := &(*l)[i]
+		if u.Type == typ && u.Project == prj && u.Workspace == ws {
+			break
+		}
+	}
+	if i == len(*l) {
+		*l = append(*l, diskUsage{Type: typ, Project: prj, Workspace: ws})
+	}
+
+	u := &(*l)[i]
+	u.Count++
+	u.Size += size
+	if reclaimable {
+		u.Reclaimable += size
+	}
+}
+
+// ownerNames returns the names of the project and workspace of the container. Containers that
+// don't belong to the current project and don't have project labels are identified by the
+// project and container ID.
+func ownerNames(prj *project.Project, ctr runtime.Container) (string, string) {
+
+	dom := ctr.Domain()
+	id := ctr.ID()
+	prjName := hex.EncodeToString(dom[:])[:displayHashLength]
+	wsName := hex.EncodeToString(id[:])[:displayHashLength]
+	if prj == nil || prj.UUID != uuid.UUID(dom).String() {
+		labels := ctr.Labels()
+		if name, ok := labels[runtime.ProjectNameLabel]; ok {
+			prjName = name
+		}
+		if name, ok := labels[runtime.WorkspaceNameLabel]; ok {
+			wsName = name
+		}
+		return prjName, wsName
+	}
+
+	for _, ws := range prj.Workspaces {
+		if ws.ID() == id {
+			return prj.Name, ws.Name
+		}
+	}
+	return prj.Name, wsName
+}
+
+// workspaceOwner returns the names of the project and workspace from the workspace label of a
+// layer snapshot. Projects not found in the provided map of project UUIDs to names are
+// identified by the project ID.
+func workspaceOwner(label string, prjNames map[string]string) (string, string, bool) {
+
+	prjUUID, wsName, ok := strings.Cut(label, "/")
+	if !ok || wsName == "" {
+		return "", "", false
+	}
+	if name, ok := prjNames[prjUUID]; ok {
+		return name, wsName, true
+	}
+	dom, err := uuid.Parse(prjUUID)
+	if err != nil {
+		return "", "", false
+	}
+	return hex.EncodeToString(dom[:])[:displayHashLength], wsName, true
+}
+
+// imageSnapshots returns the names of all snapshots that were created unpacking the images.
+func imageSnapshots(ctx context.Context, imgs []runtime.Image) (map[string]bool, error) {
+
+	names := make(map[string]bool)
+	for _, img := range imgs {
+		diffIDs, err := img.RootFS(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range identity.ChainIDs(diffIDs) {
+			names[c.String()] = true
+		}
+	}
+	return names, nil
+}
+
+// projectSnapshots returns the names of the layer snapshots referenced by the project.
+func projectSnapshots(prj *project.Project) map[string]string {
+
+	names := make(map[string]string)
+	if prj == nil {
+		return names
+	}
+	for _, ws := range prj.Workspaces {
+		for _, l := range ws.Environment.Layers {
+			if l.Digest != "" {
+				names[l.Digest] = ws.Name
+			}
+		}
+	}
+	return names
+}
+
+func diskUsages(ctx context.Context,
+	run runtime.Runtime, prj *project.Project) (diskUsageList, error) {
+
+	var usage diskUsageList
+
+	snaps, err := run.Snapshots(ctx)
+	if err != nil {
+		return nil, err
+	}
+	snapMap := make(map[string]runtime.Snapshot, len(snaps))
+	for _, s := range snaps {
+		snapMap[s.Name()] = s
+	}
+
+	imgs, err := run.Images(ctx)
+	if err != nil {
+		return nil, err
+	}
+	imgSnaps, err := imageSnapshots(ctx, imgs)
+	if err != nil {
+		return nil, err
+	}
+
+	ctrs, err := container.Containers(ctx, run, nil, &user)
+	if err != nil {
+		return nil, err
+	}
+
+	// attribute the active snapshots and layer snapshots to the containers
+	imgUsers := make(map[string][]string)
+	claimed := make(map[string]bool)
+	prjNames := make(map[string]string)
+	if prj != nil {
+		prjNames[prj.UUID] = prj.Name
+	}
+	for _, ctr := range ctrs {
+		prjName, wsName := ownerNames(prj, ctr)
+		if _, ok := ctr.Labels()[runtime.ProjectNameLabel]; ok {
+			prjNames[uuid.UUID(ctr.Domain()).String()] = prjName
+		}
+
+		if img, err := ctr.Image(ctx); err == nil {
+			imgUsers[img.Name()] = append(imgUsers[img.Name()], prjName)
+		}
+
+		snap, err := ctr.ActiveSnapshot(ctx)
+		if err != nil && errors.Is(err, errdefs.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		usage.add("container", prjName, wsName, snap.Size(), false)
+		claimed[snap.Name()] = true
+
+		for name := snap.Parent(); name != "" && !imgSnaps[name]; {
+			s, ok := snapMap[name]
+			if !ok || claimed[name] {
+				break
+			}
+			usage.add("layer", prjName, wsName, s.Size(), false)
+			claimed[name] = true
+			name = s.Parent()
+		}
+	}
+
+	// images of the current project are in use even without a container
+	if prj != nil {
+		for _, ws := range prj.Workspaces {
+			for _, ref := range []string{ws.Environment.Origin, ws.OriginReference()} {
+				name, err := conf.FullImageName(ref)
+				if err == nil {
+					imgUsers[name] = append(imgUsers[name], prj.Name)
+				}
+			}
+		}
+	}
+
+	// images shared by several projects are attributed to the first project
+	for _, img := range imgs {
+		users := imgUsers[img.Name()]
+		prjName := ""
+		if len(users) > 0 {
+			prjName = users[0]
+		}
+		usage.add("image", prjName, "", img.Size(), len(users) == 0)
+	}
+
+	// all other layer snapshots are either cached layers of a project or unreferenced;
+	// layers of other projects are attributed by the workspace label of the snapshot
+	prjSnaps := projectSnapshots(prj)
+	for _, s := range snaps {
+		if claimed[s.Name()] || imgSnaps[s.Name()] {
+			continue
+		}
+		if wsName, ok := prjSnaps[s.Name()]; ok {
+			usage.add("cache", prj.Name, wsName, s.Size(), true)
+		} else if prjName, wsName, ok := workspaceOwner(
+			s.Labels()[runtime.WorkspaceLabel], prjNames); ok {
+			usage.add("cache", prjName, wsName, s.Size(), true)
+		} else {
+			usage.add("unreferenced", "", "", s.Size(), true)
+		}
+	}
+
+	return usage, nil
+}
+
+func dfRunE(cmd *cobra.Command, args []string) error {
+
+	runCfg, err := conf.GetRuntime()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	run, err := runtime.Open(ctx, runCfg)
+	if err != nil {
+		return err
+	}
+	defer run.Close()
+	ctx = run.WithNamespace(ctx, runCfg.Namespace)
+
+	prj, err := readProject()
+	if err != nil && !errors.Is(err, errdefs.ErrNotFound) {
+		return err
+	}
+
+	usage, err := diskUsages(ctx, run, prj)
+	if err != nil {
+		return err
+	}
+
+	var total, reclaimable int64
+	usageList := make([]struct {
+		Type        string
+		Project     string
+		Workspace   string
+		Count       int
+		Size        string
+		Reclaimable string
+	}, len(usage))
+	for i, u := range usage {
+		usageList[i].Type = u.Type
+		usageList[i].Project = u.Project
+		usageList[i].Workspace = u.Workspace
+		usageList[i].Count = u.Count
+		usageList[i].Size = sizeToSIString(u.Size)
+		usageList[i].Reclaimable = sizeToSIString(u.Reclaimable)
+		total += u.Size
+		reclaimable += u.Reclaimable
+	}
+	printList(usageList, false)
+	if outputFormat != outputText {
+		return nil
+	}
+
+	fmt.Printf("\nTotal: %s, reclaimable: %s\n",
+		sizeToSIString(total), sizeToSIString(reclaimable))
+
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(dfCmd)
+}
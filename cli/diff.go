@@ -0,0 +1,133 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/czankel/cne/project"
+)
+
+var diffCmd = &cobra.Command{
+	Use:   "diff",
+	Short: "Show differences between resources",
+	Args:  cobra.MinimumNArgs(1),
+}
+
+var diffWorkspaceCmd = &cobra.Command{
+	Use:   "workspace [name] name",
+	Short: "Show the differences of the layers of two workspaces",
+	Long: `
+Show the differences of the layers and commands of two workspaces. If only one
+workspace is provided, compare the current workspace with that workspace.`,
+	Aliases: []string{"ws"},
+	Args:    cobra.RangeArgs(1, 2),
+	RunE:    diffWorkspaceRunE,
+}
+
+// diffLayerCommands returns the lines of the commands of the layer or nil for a nil layer
+func diffLayerCommands(layer *project.Layer) []string {
+
+	if layer == nil {
+		return nil
+	}
+	lines := make([]string, len(layer.Commands))
+	for i, c := range layer.Commands {
+		lines[i] = commandLine(c)
+	}
+	return lines
+}
+
+// diffWorkspaces returns the differences between the workspaces for all layers that differ
+// in the handler or any command.
+func diffWorkspaces(wsA, wsB *project.Workspace) []string {
+
+	var diff []string
+	if wsA.Base != wsB.Base {
+		diff = append(diff, fmt.Sprintf("base: '%s' -> '%s'", wsA.Base, wsB.Base))
+	}
+	if wsA.Environment.Origin != wsB.Environment.Origin {
+		diff = append(diff, fmt.Sprintf("origin: '%s' -> '%s'",
+			wsA.Environment.Origin, wsB.Environment.Origin))
+	}
+	if wsA.Environment.OriginDigest != wsB.Environment.OriginDigest {
+		diff = append(diff, fmt.Sprintf("origin digest: '%s' -> '%s'",
+			wsA.Environment.OriginDigest, wsB.Environment.OriginDigest))
+	}
+	if wsA.Environment.Platform != wsB.Environment.Platform {
+		diff = append(diff, fmt.Sprintf("platform: '%s' -> '%s'",
+			wsA.Environment.Platform, wsB.Environment.Platform))
+	}
+
+	var names []string
+	for _, l := range wsA.Environment.Layers {
+		names = append(names, l.Name)
+	}
+	for _, l := range wsB.Environment.Layers {
+		if _, _, err := wsA.FindLayer(l.Name); err != nil {
+			names = append(names, l.Name)
+		}
+	}
+
+	for _, name := range names {
+		idxA, layerA, _ := wsA.FindLayer(name)
+		idxB, layerB, _ := wsB.FindLayer(name)
+
+		note := ""
+		if layerB == nil {
+			note = " (only in " + wsA.Name + ")"
+		} else if layerA == nil {
+			note = " (only in " + wsB.Name + ")"
+		} else if layerA.Handler != layerB.Handler {
+			note = fmt.Sprintf(" (handler '%s' -> '%s')", layerA.Handler, layerB.Handler)
+		} else if idxA != idxB {
+			note = fmt.Sprintf(" (position %d -> %d)", idxA, idxB)
+		}
+
+		lines := diffLines(diffLayerCommands(layerA), diffLayerCommands(layerB))
+		changed := note != ""
+		for _, l := range lines {
+			if l[0] != ' ' {
+				changed = true
+				break
+			}
+		}
+		if changed {
+			diff = append(diff, "layer "+name+note)
+			diff = append(diff, lines...)
+		}
+	}
+	return diff
+}
+
+func diffWorkspaceRunE(cmd *cobra.Command, args []string) error {
+
+	prj, err := readProject()
+	if err != nil {
+		return err
+	}
+
+	var wsA *project.Workspace
+	if len(args) == 1 {
+		wsA, err = prj.CurrentWorkspace()
+	} else {
+		wsA, err = prj.Workspace(args[0])
+	}
+	if err != nil {
+		return err
+	}
+	wsB, err := prj.Workspace(args[len(args)-1])
+	if err != nil {
+		return err
+	}
+
+	for _, l := range diffWorkspaces(wsA, wsB) {
+		fmt.Println(l)
+	}
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(diffCmd)
+	diffCmd.AddCommand(diffWorkspaceCmd)
+}
@@ -0,0 +1,243 @@
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"reflect"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	"github.com/czankel/cne/container"
+	"github.com/czankel/cne/errdefs"
+	"github.com/czankel/cne/project"
+)
+
+const editDefaultEditor = "vi"
+
+// editLayer is the layer definition presented in the editor, which excludes the layer snapshot.
+// Opaque is read-only, as snapshot layers cannot be rebuilt from their commands.
+type editLayer struct {
+	Name     string
+	Handler  string `yaml:",omitempty"`
+	Disabled bool   `yaml:",omitempty"`
+	Opaque   bool   `yaml:",omitempty"`
+	Commands []project.Command
+}
+
+// runEditor opens the provided text in the editor defined by $VISUAL or $EDITOR and returns
+// the edited text.
+func runEditor(text []byte) ([]byte, error) {
+
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = editDefaultEditor
+	}
+
+	file, err := os.CreateTemp("", "cne-edit-*.yaml")
+	if err != nil {
+		return nil, errdefs.SystemError(err, "failed to create temporary file")
+	}
+	defer os.Remove(file.Name())
+
+	_, err = file.Write(text)
+	file.Close()
+	if err != nil {
+		return nil, errdefs.SystemError(err, "failed to write temporary file")
+	}
+
+	// the editor setting can include arguments
+	args := strings.Fields(editor)
+	cmd := exec.Command(args[0], append(args[1:], file.Name())...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return nil, errdefs.SystemError(err, "editor '%s' failed", editor)
+	}
+
+	text, err = os.ReadFile(file.Name())
+	if err != nil {
+		return nil, errdefs.SystemError(err, "failed to read temporary file")
+	}
+	return text, nil
+}
+
+// editValue opens the value as YAML in the editor until the edited text can be parsed and
+// validated or the user discards the changes. It returns false if the value wasn't changed.
+func editValue(header string, value interface{}, validate func() error) (bool, error) {
+
+	orig, err := yaml.Marshal(value)
+	if err != nil {
+		return false, errdefs.InternalError("failed to marshal value: %v", err)
+	}
+
+	text := append([]byte(header), orig...)
+	reader := bufio.NewReader(os.Stdin)
+	for {
+		text, err = runEditor(text)
+		if err != nil {
+			return false, err
+		}
+
+		// decode into an empty value to drop all removed entries
+		elem := reflect.ValueOf(value).Elem()
+		elem.Set(reflect.Zero(elem.Type()))
+		err = yaml.Unmarshal(text, value)
+		if err != nil {
+			err = errdefs.InvalidArgument("invalid YAML: %v", err)
+		} else {
+			err = validate()
+		}
+		if err == nil {
+			break
+		}
+
+		fmt.Printf("%v\nEdit again? [Y/n] ", err)
+		answer, readErr := reader.ReadString('\n')
+		answer = strings.ToLower(strings.TrimSpace(answer))
+		if readErr != nil || answer != "" && answer != "y" && answer != "yes" {
+			return false, err
+		}
+	}
+
+	edited, err := yaml.Marshal(value)
+	if err != nil {
+		return false, errdefs.InternalError("failed to marshal value: %v", err)
+	}
+	return string(edited) != string(orig), nil
+}
+
+var editCmd = &cobra.Command{
+	Use:   "edit",
+	Short: "Edit resources in an editor",
+	Long: `
+Edit resources in the editor defined by the VISUAL or EDITOR environment variable.
+The changes are validated before they are applied and the layers are rebuilt
+starting with the first changed layer.`,
+	Args: cobra.MinimumNArgs(1),
+}
+
+var editLayerCmd = &cobra.Command{
+	Use:     "layer [name]",
+	Short:   "Edit the commands of a layer",
+	Aliases: []string{"l"},
+	Args:    cobra.MaximumNArgs(1),
+	RunE:    editLayerRunE,
+}
+
+var editLayerWorkspace string
+
+func editLayerRunE(cmd *cobra.Command, args []string) error {
+
+	prj, err := loadProject()
+	if err != nil {
+		return err
+	}
+
+	layerName := ""
+	if len(args) > 0 {
+		layerName = args[0]
+	}
+	ws, layer, err := getLayer(prj, editLayerWorkspace, layerName)
+	if err != nil {
+		return err
+	}
+
+	header := fmt.Sprintf("# Commands of layer '%s' in workspace '%s'\n", layer.Name, ws.Name)
+	commands := append([]project.Command{}, layer.Commands...)
+
+	changed, err := editValue(header, &commands, func() error {
+		return container.ValidateCommands(ws, &user, &params, commands)
+	})
+	if err != nil || !changed {
+		return err
+	}
+
+	ws.SetLayerCommands(layer, commands)
+	return prj.Write()
+}
+
+var editWorkspaceCmd = &cobra.Command{
+	Use:     "workspace [name]",
+	Short:   "Edit the layers of a workspace",
+	Aliases: []string{"ws"},
+	Args:    cobra.MaximumNArgs(1),
+	RunE:    editWorkspaceRunE,
+}
+
+func editWorkspaceRunE(cmd *cobra.Command, args []string) error {
+
+	prj, err := loadProject()
+	if err != nil {
+		return err
+	}
+
+	var ws *project.Workspace
+	if len(args) > 0 {
+		ws, err = prj.Workspace(args[0])
+	} else {
+		ws, err = prj.CurrentWorkspace()
+	}
+	if err != nil {
+		return err
+	}
+
+	header := fmt.Sprintf("# Layers of workspace '%s'\n", ws.Name)
+	var layers []editLayer
+	for _, l := range ws.Environment.Layers {
+		layers = append(layers, editLayer{
+			Name:     l.Name,
+			Handler:  l.Handler,
+			Disabled: l.Disabled,
+			Opaque:   l.Opaque,
+			Commands: l.Commands,
+		})
+	}
+
+	var newLayers []project.Layer
+	changed, err := editValue(header, &layers, func() error {
+		newLayers = make([]project.Layer, len(layers))
+		for i, l := range layers {
+			err := container.ValidateCommands(ws, &user, &params, l.Commands)
+			if err != nil {
+				return errdefs.InvalidArgument("layer '%s': %v", l.Name, err)
+			}
+			newLayers[i] = project.Layer{
+				Name:     l.Name,
+				Handler:  l.Handler,
+				Disabled: l.Disabled,
+				Opaque:   l.Opaque,
+				Commands: l.Commands,
+			}
+		}
+		if err := project.CheckLayerNames(newLayers); err != nil {
+			return err
+		}
+		return ws.CheckOpaqueLayers(newLayers)
+	})
+	if err != nil || !changed {
+		return err
+	}
+
+	if err := ws.SetLayers(newLayers); err != nil {
+		return err
+	}
+	return prj.Write()
+}
+
+func init() {
+	rootCmd.AddCommand(editCmd)
+
+	editCmd.AddCommand(editLayerCmd)
+	editLayerCmd.Flags().StringVarP(
+		&editLayerWorkspace, "workspace", "w", "", "Name of the workspace")
+
+	editCmd.AddCommand(editWorkspaceCmd)
+}
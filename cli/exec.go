@@ -26,13 +26,33 @@ var execCmd = &cobra.Command{
 var execShell bool
 var execLayerName string
 var execTestOnly bool
+var execWorkspace string
 
 // execCommandsInShell executes the provided commands in a shell.
 func execCommandsInShell(wsName, layerName string, args []string) (int, error) {
-	args = append([]string{"/bin/sh", "-c", strings.Join(args, " ")})
+	args = []string{"/bin/sh", "-c", strings.Join(args, " ")}
 	return execCommands(wsName, layerName, args)
 }
 
+// getExecContainer returns the active container for the workspace and builds the container
+// if it doesn't exist.
+func getExecContainer(ctx context.Context, run runtime.Runtime,
+	prj *project.Project, ws *project.Workspace) (runtime.Container, error) {
+
+	ctr, err := container.GetContainer(ctx, run, ws)
+	if err != nil && !errors.Is(err, errdefs.ErrNotFound) {
+		return nil, err
+	}
+	if errors.Is(err, errdefs.ErrNotFound) {
+		ctr, err = buildContainer(ctx, run, ws, -1)
+		if err != nil {
+			return nil, err
+		}
+		prj.Write()
+	}
+	return ctr, nil
+}
+
 // execCommands executes the provided commands in the current or provided workspace.
 // It returns a code != 0 if the executed command failed. The returned 'code' value
 // is the value returned by the command. The caller should call exit(code) to have a
@@ -61,9 +81,11 @@ func execCommands(wsName, layerName string, args []string) (int, error) {
 	var ws *project.Workspace
 	if wsName == "" {
 		ws, err = prj.CurrentWorkspace()
-		if err != nil {
-			return 0, err
-		}
+	} else {
+		ws, err = prj.Workspace(wsName)
+	}
+	if err != nil {
+		return 0, err
 	}
 
 	stream := runtime.Stream{
@@ -81,18 +103,12 @@ func execCommands(wsName, layerName string, args []string) (int, error) {
 	winSz, _ := con.Size()
 	con.Resize(winSz)
 
-	if execLayerName == "" {
-		ctr, err := container.GetContainer(ctx, run, ws)
-		if err != nil && !errors.Is(err, errdefs.ErrNotFound) {
+	if layerName == "" {
+		ctr, err := getExecContainer(ctx, run, prj, ws)
+		if err != nil {
 			return 0, err
 		}
-		if errors.Is(err, errdefs.ErrNotFound) {
-			ctr, err = buildContainer(ctx, run, ws, -1)
-			if err != nil {
-				return 0, err
-			}
-			prj.Write()
-		}
+		unlockProject()
 
 		code, err := container.Exec(ctx, ctr, &user, stream, args)
 		if err != nil && errors.Is(err, errdefs.ErrNotFound) && errdefs.Resource(err) == "command" {
@@ -104,7 +120,7 @@ func execCommands(wsName, layerName string, args []string) (int, error) {
 
 	} else {
 
-		layerIdx, layer, err := ws.FindLayer(execLayerName)
+		layerIdx, layer, err := ws.FindLayer(layerName)
 		if err != nil {
 			return 0, err
 		}
@@ -125,7 +141,7 @@ func execCommands(wsName, layerName string, args []string) (int, error) {
 		if !execTestOnly {
 
 			layer.Commands = append(layer.Commands,
-				project.Command{"", []string{}, args})
+				project.Command{Envs: []string{}, Args: args})
 
 			snap, err := ctr.Amend(ctx)
 			if err != nil && !errors.Is(err, errdefs.ErrAlreadyExists) {
@@ -152,9 +168,9 @@ func execRunE(cmd *cobra.Command, args []string) error {
 	var err error
 
 	if execShell {
-		code, err = execCommandsInShell("", "", args)
+		code, err = execCommandsInShell(execWorkspace, execLayerName, args)
 	} else {
-		code, err = execCommands("", "", args)
+		code, err = execCommands(execWorkspace, execLayerName, args)
 	}
 	if code != 0 {
 		os.Exit(code)
@@ -169,5 +185,7 @@ func init() {
 		"Execute a command in this layer to rebuild the layer and amend the project")
 	execCmd.Flags().BoolVar(&execTestOnly, "test-only", false,
 		"Don't amend the layer")
+	execCmd.Flags().StringVarP(&execWorkspace, "workspace", "w", "",
+		"Execute the command in this workspace instead of the current workspace")
 	rootCmd.AddCommand(execCmd)
 }
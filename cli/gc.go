@@ -0,0 +1,387 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/opencontainers/image-spec/identity"
+	"github.com/spf13/cobra"
+
+	"github.com/czankel/cne/errdefs"
+	"github.com/czankel/cne/project"
+	"github.com/czankel/cne/runtime"
+)
+
+var gcCmd = &cobra.Command{
+	Use:   "gc",
+	Short: "Remove unused snapshots and images based on retention policies",
+	Long: `
+Remove snapshots and images according to the provided retention policies while
+keeping the snapshots used by containers and referenced by the known projects.
+Known projects are the current project and the projects provided with the
+--project option.
+
+  --keep N          keep the last N generations of the layer snapshots of each
+                    workspace; a generation is the set of layer snapshots of
+                    one build of the workspace
+  --unreferenced    remove all snapshots not referenced by any known project
+                    and not used by any container
+  --unused-images D remove images that are not used by any known project or
+                    container and were last used more than the duration D ago;
+                    an image is used when it is pulled or imported or when a
+                    layer is built on top of it
+
+Use --dry-run to list the snapshots and images that would be removed.`,
+	Args: cobra.NoArgs,
+	RunE: gcRunE,
+}
+
+var gcKeep int
+var gcUnreferenced bool
+var gcUnusedImages time.Duration
+var gcProjects []string
+var gcDryRun bool
+
+// gcProtected returns the snapshots that must be kept because they are used by a container,
+// referenced by a known project, or part of an image, including all their ancestors.
+func gcProtected(ctx context.Context, run runtime.Runtime, snapMap map[string]runtime.Snapshot,
+	imgSnaps map[string]bool, prjs []*project.Project) (map[string]bool, error) {
+
+	protected := make(map[string]bool)
+	protect := func(name string) {
+		for name != "" && !protected[name] {
+			protected[name] = true
+			s, ok := snapMap[name]
+			if !ok {
+				break
+			}
+			name = s.Parent()
+		}
+	}
+
+	for name := range imgSnaps {
+		protect(name)
+	}
+
+	// all containers of all users
+	ctrs, err := run.Containers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, ctr := range ctrs {
+		snap, err := ctr.ActiveSnapshot(ctx)
+		if err != nil && errors.Is(err, errdefs.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		protect(snap.Name())
+	}
+
+	for _, prj := range prjs {
+		for name := range projectSnapshots(prj) {
+			protect(name)
+		}
+	}
+
+	return protected, nil
+}
+
+// gcGenerations returns the layer snapshots of older generations of the workspaces that exceed
+// the number of generations to keep. Snapshots of the kept generations are added to the
+// protected snapshots.
+func gcGenerations(snaps []runtime.Snapshot, snapMap map[string]runtime.Snapshot,
+	protected map[string]bool, keep int) []string {
+
+	// find the topmost snapshot of each generation in each workspace
+	hasChild := make(map[string]bool)
+	for _, s := range snaps {
+		label := s.Labels()[runtime.WorkspaceLabel]
+		if p, ok := snapMap[s.Parent()]; ok && label != "" &&
+			p.Labels()[runtime.WorkspaceLabel] == label {
+			hasChild[p.Name()] = true
+		}
+	}
+	generations := make(map[string][]runtime.Snapshot)
+	for _, s := range snaps {
+		label := s.Labels()[runtime.WorkspaceLabel]
+		if label != "" && !hasChild[s.Name()] {
+			generations[label] = append(generations[label], s)
+		}
+	}
+
+	var candidates []string
+	for label, leaves := range generations {
+		sort.Slice(leaves, func(i, j int) bool {
+			return leaves[i].CreatedAt().After(leaves[j].CreatedAt())
+		})
+
+		// keep the snapshots of the newest generations
+		n := keep
+		if n > len(leaves) {
+			n = len(leaves)
+		}
+		for _, leaf := range leaves[:n] {
+			for s := leaf; s != nil && !protected[s.Name()]; s = snapMap[s.Parent()] {
+				protected[s.Name()] = true
+			}
+		}
+
+		for _, leaf := range leaves[n:] {
+			for s := leaf; s != nil && s.Labels()[runtime.WorkspaceLabel] == label; {
+				if !protected[s.Name()] {
+					candidates = append(candidates, s.Name())
+				}
+				s = snapMap[s.Parent()]
+			}
+		}
+	}
+	return candidates
+}
+
+// snapshotsLastUsed returns for each snapshot the newest creation time of the snapshot and
+// all snapshots derived from it.
+func snapshotsLastUsed(snaps []runtime.Snapshot,
+	snapMap map[string]runtime.Snapshot) map[string]time.Time {
+
+	lastUsed := make(map[string]time.Time)
+	for _, s := range snaps {
+		createdAt := s.CreatedAt()
+		for name := s.Name(); name != ""; {
+			if t, ok := lastUsed[name]; ok && !t.Before(createdAt) {
+				break
+			}
+			lastUsed[name] = createdAt
+			p, ok := snapMap[name]
+			if !ok {
+				break
+			}
+			name = p.Parent()
+		}
+	}
+	return lastUsed
+}
+
+// imageLastUsed returns the time the image was last used, which is the newest of the time the
+// image was pulled or imported and the time a snapshot was built on top of the image.
+func imageLastUsed(ctx context.Context, img runtime.Image,
+	lastUsed map[string]time.Time) (time.Time, error) {
+
+	diffIDs, err := img.RootFS(ctx)
+	if err != nil {
+		return time.Time{}, err
+	}
+	t := img.UpdatedAt()
+	if s, ok := lastUsed[identity.ChainID(diffIDs).String()]; ok && s.After(t) {
+		t = s
+	}
+	return t, nil
+}
+
+// gcSnapshots removes the snapshots starting with the snapshots that don't have any children and
+// returns the removed snapshots. Snapshots that still have children are kept.
+func gcSnapshots(ctx context.Context, run runtime.Runtime, snaps []runtime.Snapshot,
+	candidates map[string]runtime.Snapshot) ([]runtime.Snapshot, error) {
+
+	children := make(map[string]int)
+	for _, s := range snaps {
+		children[s.Parent()]++
+	}
+
+	var removed []runtime.Snapshot
+	for done := false; !done; {
+		done = true
+		for name, s := range candidates {
+			if children[name] != 0 {
+				continue
+			}
+			if !gcDryRun {
+				err := run.DeleteSnapshot(ctx, name)
+				if err != nil && errors.Is(err, errdefs.ErrInUse) {
+					delete(candidates, name)
+					continue
+				}
+				if err != nil && !errors.Is(err, errdefs.ErrNotFound) {
+					return removed, err
+				}
+			}
+			removed = append(removed, s)
+			delete(candidates, name)
+			children[s.Parent()]--
+			done = false
+		}
+	}
+	return removed, nil
+}
+
+func gcRunE(cmd *cobra.Command, args []string) error {
+
+	if gcKeep < 0 && !gcUnreferenced && gcUnusedImages == 0 {
+		return errdefs.InvalidArgument("no retention policy provided")
+	}
+
+	runCfg, err := conf.GetRuntime()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	run, err := runtime.Open(ctx, runCfg)
+	if err != nil {
+		return err
+	}
+	defer run.Close()
+	ctx = run.WithNamespace(ctx, runCfg.Namespace)
+
+	var prjs []*project.Project
+	prj, err := readProject()
+	if err != nil && !errors.Is(err, errdefs.ErrNotFound) {
+		return err
+	}
+	if prj != nil {
+		prjs = append(prjs, prj)
+	}
+
+	for _, p := range gcProjects {
+		path, err := project.GetProjectPath(p)
+		if err != nil {
+			return err
+		}
+		prj, err := project.Load(path)
+		if err != nil {
+			return err
+		}
+		prjs = append(prjs, prj)
+	}
+
+	snaps, err := run.Snapshots(ctx)
+	if err != nil {
+		return err
+	}
+	snapMap := make(map[string]runtime.Snapshot, len(snaps))
+	for _, s := range snaps {
+		snapMap[s.Name()] = s
+	}
+
+	imgs, err := run.Images(ctx)
+	if err != nil {
+		return err
+	}
+	imgSnaps, err := imageSnapshots(ctx, imgs)
+	if err != nil {
+		return err
+	}
+
+	protected, err := gcProtected(ctx, run, snapMap, imgSnaps, prjs)
+	if err != nil {
+		return err
+	}
+
+	candidates := make(map[string]runtime.Snapshot)
+	if gcKeep >= 0 {
+		for _, name := range gcGenerations(snaps, snapMap, protected, gcKeep) {
+			candidates[name] = snapMap[name]
+		}
+	}
+	if gcUnreferenced {
+		for _, s := range snaps {
+			if !protected[s.Name()] {
+				candidates[s.Name()] = s
+			}
+		}
+	}
+
+	removed, err := gcSnapshots(ctx, run, snaps, candidates)
+	if err != nil {
+		return err
+	}
+
+	type gcItem struct {
+		Type string
+		Name string
+		Size string
+	}
+	removedList := []gcItem{}
+	var total int64
+	for _, s := range removed {
+		removedList = append(removedList,
+			gcItem{Type: "snapshot", Name: s.Name(), Size: sizeToSIString(s.Size())})
+		total += s.Size()
+	}
+
+	if gcUnusedImages != 0 {
+		used := make(map[string]bool)
+		ctrs, err := run.Containers(ctx)
+		if err != nil {
+			return err
+		}
+		for _, ctr := range ctrs {
+			if img, err := ctr.Image(ctx); err == nil {
+				used[img.Name()] = true
+			}
+		}
+		for _, prj := range prjs {
+			for _, ws := range prj.Workspaces {
+				for _, ref := range []string{ws.Environment.Origin, ws.OriginReference()} {
+					if name, err := conf.FullImageName(ref); err == nil {
+						used[name] = true
+					}
+				}
+			}
+		}
+
+		lastUsed := snapshotsLastUsed(snaps, snapMap)
+		for _, img := range imgs {
+			if used[img.Name()] {
+				continue
+			}
+			t, err := imageLastUsed(ctx, img, lastUsed)
+			if err != nil {
+				return err
+			}
+			if time.Since(t) < gcUnusedImages {
+				continue
+			}
+			if !gcDryRun {
+				err = run.DeleteImage(ctx, img.Name())
+				if err != nil {
+					return err
+				}
+			}
+			removedList = append(removedList,
+				gcItem{Type: "image", Name: img.Name(), Size: sizeToSIString(img.Size())})
+			total += img.Size()
+		}
+	}
+
+	if gcDryRun && outputFormat == outputText {
+		fmt.Printf("Would remove:\n")
+	}
+	printList(removedList, false)
+	if outputFormat != outputText {
+		return nil
+	}
+	fmt.Printf("\nTotal reclaimed: %s\n", sizeToSIString(total))
+
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(gcCmd)
+	gcCmd.Flags().IntVar(
+		&gcKeep, "keep", -1, "Keep the last N generations of layer snapshots per workspace")
+	gcCmd.Flags().BoolVar(
+		&gcUnreferenced, "unreferenced", false,
+		"Remove snapshots not referenced by any known project")
+	gcCmd.Flags().DurationVar(
+		&gcUnusedImages, "unused-images", 0,
+		"Remove unused images last used more than the duration ago")
+	gcCmd.Flags().StringSliceVarP(
+		&gcProjects, "project", "p", []string{}, "Path of an additional known project")
+	gcCmd.Flags().BoolVar(
+		&gcDryRun, "dry-run", false, "List the snapshots and images without removing them")
+}
@@ -0,0 +1,152 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/czankel/cne/errdefs"
+	"github.com/czankel/cne/runtime"
+)
+
+var imageCmd = &cobra.Command{
+	Use:   "image",
+	Short: "Load and save images",
+	Args:  cobra.MinimumNArgs(1),
+}
+
+// loadImages loads the images for the platform from the archive file.
+func loadImages(ctx context.Context,
+	run runtime.Runtime, path, platform string) ([]runtime.Image, error) {
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, errdefs.InvalidArgument("failed to open '%s': %v", path, err)
+	}
+	defer file.Close()
+
+	return run.ImportImage(ctx, file, platform)
+}
+
+// saveImage saves the image for the platform to the archive file. The file is removed if the
+// image cannot be saved.
+func saveImage(ctx context.Context,
+	run runtime.Runtime, imgName, platform, path string) error {
+
+	file, err := os.Create(path)
+	if err != nil {
+		return errdefs.InvalidArgument("failed to create '%s': %v", path, err)
+	}
+
+	err = run.ExportImage(ctx, imgName, platform, file)
+	if cerr := file.Close(); err == nil && cerr != nil {
+		err = errdefs.SystemError(cerr, "failed to write '%s'", path)
+	}
+	if err != nil {
+		os.Remove(path)
+	}
+	return err
+}
+
+var imageLoadCmd = &cobra.Command{
+	Use:   "load",
+	Short: "Load images from an archive",
+	Long: `
+Load the images from a tar archive in the OCI or Docker image format, for
+example, an archive written by 'image save', for systems that cannot pull
+images from a registry. The images are unpacked when building a workspace.
+Only the images for the platform of the host or the provided platform are
+loaded.`,
+	Args: cobra.NoArgs,
+	RunE: imageLoadRunE,
+}
+
+var imageLoadInput string
+var imageLoadPlatform string
+
+func imageLoadRunE(cmd *cobra.Command, args []string) error {
+
+	runCfg, err := conf.GetRuntime()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	run, err := runtime.Open(ctx, runCfg)
+	if err != nil {
+		return err
+	}
+	defer run.Close()
+	ctx = run.WithNamespace(ctx, runCfg.Namespace)
+
+	imgs, err := loadImages(ctx, run, imageLoadInput, imageLoadPlatform)
+	if err != nil {
+		return err
+	}
+
+	for _, img := range imgs {
+		fmt.Printf("Loaded image '%s'\n", img.Name())
+	}
+	return nil
+}
+
+var imageSaveCmd = &cobra.Command{
+	Use:   "save name",
+	Short: "Save an image to an archive",
+	Long: `
+Save the image for the platform of the host or the provided platform to a tar
+archive in the OCI image format. Use 'image load' to load the image on another
+system.`,
+	Args: cobra.ExactArgs(1),
+	RunE: imageSaveRunE,
+}
+
+var imageSaveFile string
+var imageSavePlatform string
+
+func imageSaveRunE(cmd *cobra.Command, args []string) error {
+
+	runCfg, err := conf.GetRuntime()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	run, err := runtime.Open(ctx, runCfg)
+	if err != nil {
+		return err
+	}
+	defer run.Close()
+	ctx = run.WithNamespace(ctx, runCfg.Namespace)
+
+	imgName, err := getImageName(ctx, run, args[0])
+	if err != nil {
+		return err
+	}
+
+	err = saveImage(ctx, run, imgName, imageSavePlatform, imageSaveFile)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Saved image '%s' to '%s'\n", imgName, imageSaveFile)
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(imageCmd)
+	imageCmd.AddCommand(imageLoadCmd)
+	imageLoadCmd.Flags().StringVarP(
+		&imageLoadInput, "input", "i", "", "Name of the archive file")
+	imageLoadCmd.MarkFlagRequired("input")
+	imageLoadCmd.Flags().StringVar(
+		&imageLoadPlatform, "platform", "", "Platform of the images, for example, linux/arm64")
+	imageCmd.AddCommand(imageSaveCmd)
+	imageSaveCmd.Flags().StringVarP(
+		&imageSaveFile, "file", "o", "", "Name of the archive file")
+	imageSaveCmd.MarkFlagRequired("file")
+	imageSaveCmd.Flags().StringVar(
+		&imageSavePlatform, "platform", "", "Platform of the image, for example, linux/arm64")
+}
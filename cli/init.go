@@ -12,6 +12,7 @@ import (
 )
 
 var initProjectImage string
+var initProjectPlatform string
 
 var initCmd = &cobra.Command{
 	Use:   "init [name]",
@@ -52,7 +53,7 @@ func initProjectRunE(cmd *cobra.Command, args []string) error {
 
 	if initProjectImage != "" {
 		err = initWorkspace(prj, project.WorkspaceDefaultName,
-			"" /* Insert */, initProjectImage)
+			"" /* Insert */, initProjectImage, initProjectPlatform)
 		if err != nil {
 			prj.Delete()
 			return err
@@ -66,4 +67,6 @@ func init() {
 	rootCmd.AddCommand(initCmd)
 	initCmd.Flags().StringVar(
 		&initProjectImage, "image", "", "Base image")
+	initCmd.Flags().StringVar(
+		&initProjectPlatform, "platform", "", "Platform of the base image, for example, linux/arm64")
 }
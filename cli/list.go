@@ -5,6 +5,7 @@ import (
 	"errors"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 
@@ -123,7 +124,7 @@ var listCommandsLayer string
 
 func listCommandsRunE(cmd *cobra.Command, args []string) error {
 
-	prj, err := loadProject()
+	prj, err := readProject()
 	if err != nil {
 		return err
 	}
@@ -157,12 +158,21 @@ func listContainers(ctx context.Context, run runtime.Runtime, prj *project.Proje
 
 	ctrList := make([]struct {
 		Name      string
-		CreatedAt string
+		Project   string
+		Workspace string
+		Path      string
+		CreatedAt time.Time
+		UpdatedAt time.Time
 	}, len(ctrs), len(ctrs))
 
 	for i, c := range ctrs {
+		labels := c.Labels()
 		ctrList[i].Name = c.Name()
-		ctrList[i].CreatedAt = timeToAgoString(c.CreatedAt())
+		ctrList[i].Project = labels[runtime.ProjectNameLabel]
+		ctrList[i].Workspace = labels[runtime.WorkspaceNameLabel]
+		ctrList[i].Path = labels[runtime.ProjectPathLabel]
+		ctrList[i].CreatedAt = c.CreatedAt()
+		ctrList[i].UpdatedAt = c.UpdatedAt()
 	}
 
 	printList(ctrList, false)
@@ -188,7 +198,7 @@ func listContainersRunE(cmd *cobra.Command, args []string) error {
 	ctx = run.WithNamespace(ctx, runCfg.Namespace)
 
 	if !listContainersAll {
-		prj, err = loadProject()
+		prj, err = readProject()
 		if err != nil && !errors.Is(err, errdefs.ErrNotFound) {
 			return err
 		}
@@ -248,7 +258,7 @@ func listImages(ctx context.Context, run runtime.Runtime) error {
 		Name      string
 		Tag       string
 		ID        string
-		CreatedAt string
+		CreatedAt time.Time
 		Size      string
 	}, len(images), len(images))
 	for i, img := range images {
@@ -262,7 +272,7 @@ func listImages(ctx context.Context, run runtime.Runtime) error {
 		} else {
 			imgList[i].ID = ""
 		}
-		imgList[i].CreatedAt = timeToAgoString(img.CreatedAt())
+		imgList[i].CreatedAt = img.CreatedAt()
 		imgList[i].Size = sizeToSIString(img.Size())
 	}
 	printList(imgList, true)
@@ -301,7 +311,7 @@ func listRegistryRunE(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return err
 	}
-	printList(cfg.Registry, false)
+	printList(maskRegistryTokens(cfg.Registry), false)
 	return nil
 }
 
@@ -340,7 +350,7 @@ func listSnapshots(ctx context.Context, run runtime.Runtime) error {
 	snapList := make([]struct {
 		Name      string
 		Parent    string
-		CreatedAt string
+		CreatedAt time.Time
 		Size      int64
 		Inodes    int64
 	}, len(snapshots), len(snapshots))
@@ -348,7 +358,7 @@ func listSnapshots(ctx context.Context, run runtime.Runtime) error {
 	for i, snap := range snapshots {
 		snapList[i].Name = snap.Name()
 		snapList[i].Parent = snap.Parent()
-		snapList[i].CreatedAt = timeToAgoString(snap.CreatedAt())
+		snapList[i].CreatedAt = snap.CreatedAt()
 		snapList[i].Size = snap.Size()
 		snapList[i].Inodes = snap.Inodes()
 	}
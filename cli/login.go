@@ -0,0 +1,168 @@
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+
+	"github.com/czankel/cne/config"
+	"github.com/czankel/cne/errdefs"
+)
+
+// registryHost returns the domain of the configured registry with the provided name, the
+// domain of the registry of the current context if the name is empty, or the name itself.
+func registryHost(name string) (string, error) {
+
+	if name == "" {
+		reg, err := conf.GetRegistry()
+		if err != nil {
+			return "", err
+		}
+		return reg.Domain, nil
+	}
+	if reg, ok := conf.Registry[name]; ok {
+		return reg.Domain, nil
+	}
+	return name, nil
+}
+
+// maskedToken replaces registry tokens in the output of the configuration
+const maskedToken = "********"
+
+// maskRegistryTokens returns a copy of the registries with the tokens masked for printing.
+func maskRegistryTokens(regs map[string]*config.Registry) map[string]*config.Registry {
+
+	masked := make(map[string]*config.Registry, len(regs))
+	for name, reg := range regs {
+		if reg == nil {
+			continue
+		}
+		r := *reg
+		if r.Token != "" {
+			r.Token = maskedToken
+		}
+		masked[name] = &r
+	}
+	return masked
+}
+
+var loginCmd = &cobra.Command{
+	Use:   "login [registry]",
+	Short: "Log in to a registry",
+	Long: `
+Store the credentials for a registry for pulling images. REGISTRY can be one
+of the configured registries or the domain of a registry. If omitted, the
+registry of the current context is used.
+
+The credentials are stored with the credential helper configured in the docker
+configuration file ~/.docker/config.json or in that file itself, and are shared
+with other container tools. Credentials can also be configured for a registry
+with the Username and Token settings in the user configuration.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: loginRunE,
+}
+
+var loginUsername string
+var loginPasswordStdin bool
+
+func loginRunE(cmd *cobra.Command, args []string) error {
+
+	name := ""
+	if len(args) > 0 {
+		name = args[0]
+	}
+	host, err := registryHost(name)
+	if err != nil {
+		return err
+	}
+
+	isTerminal := term.IsTerminal(int(os.Stdin.Fd()))
+	username := loginUsername
+	if username == "" && !isTerminal {
+		return errdefs.InvalidArgument("username required")
+	}
+	if username == "" {
+		fmt.Printf("Username: ")
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil {
+			return errdefs.InvalidArgument("failed to read username: %v", err)
+		}
+		username = strings.TrimSpace(line)
+	}
+
+	var secret string
+	if loginPasswordStdin {
+		input, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return errdefs.InvalidArgument("failed to read password: %v", err)
+		}
+		secret = strings.TrimRight(string(input), "\r\n")
+	} else if isTerminal {
+		fmt.Printf("Password: ")
+		input, err := term.ReadPassword(int(os.Stdin.Fd()))
+		fmt.Println()
+		if err != nil {
+			return errdefs.InvalidArgument("failed to read password: %v", err)
+		}
+		secret = string(input)
+	} else {
+		return errdefs.InvalidArgument("use --password-stdin to provide the password")
+	}
+
+	if username == "" || secret == "" {
+		return errdefs.InvalidArgument("username and password must not be empty")
+	}
+
+	err = config.Login(&user, host, username, secret)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Stored credentials for '%s'\n", host)
+	return nil
+}
+
+var logoutCmd = &cobra.Command{
+	Use:   "logout [registry]",
+	Short: "Log out from a registry",
+	Long: `
+Remove the stored credentials for a registry. REGISTRY can be one of the
+configured registries or the domain of a registry. If omitted, the registry of
+the current context is used.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: logoutRunE,
+}
+
+func logoutRunE(cmd *cobra.Command, args []string) error {
+
+	name := ""
+	if len(args) > 0 {
+		name = args[0]
+	}
+	host, err := registryHost(name)
+	if err != nil {
+		return err
+	}
+
+	err = config.Logout(&user, host)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Removed credentials for '%s'\n", host)
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(loginCmd)
+	loginCmd.Flags().StringVarP(
+		&loginUsername, "username", "u", "", "Name of the user")
+	loginCmd.Flags().BoolVar(
+		&loginPasswordStdin, "password-stdin", false, "Read the password from stdin")
+	rootCmd.AddCommand(logoutCmd)
+}
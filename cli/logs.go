@@ -0,0 +1,245 @@
+package cli
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/czankel/cne/errdefs"
+	"github.com/czankel/cne/project"
+	"github.com/czankel/cne/runtime"
+)
+
+// buildLogCount is the number of builds for which the logs are kept for each workspace
+const buildLogCount = 10
+
+// buildLogGeneration is the file in the log directory with the generation of the build
+const buildLogGeneration = "generation"
+
+// buildLog writes the output of the commands of each layer to a separate log file in the log
+// directory of the build and keeps the last lines in a ring buffer.
+type buildLog struct {
+	dir    string
+	rb     *RingBuffer
+	file   *os.File
+	layers int
+}
+
+// workspaceLogDir returns the directory with the build logs of the workspace.
+func workspaceLogDir(ws *project.Workspace) string {
+	return filepath.Join(conf.GetStateDir(&user), "logs", ws.ProjectUUID, ws.Name)
+}
+
+// newBuildLog creates the log directory for a new build of the workspace and removes the logs
+// of older builds. Build logs are disabled if the directory cannot be created.
+func newBuildLog(ws *project.Workspace) *buildLog {
+
+	lines := conf.Settings.FailureLines
+	if lines <= 0 {
+		lines = outputLineCount
+	}
+	bl := &buildLog{rb: NewRingBuffer(lines+1, outputLineLength)}
+
+	wsDir := workspaceLogDir(ws)
+	dir := filepath.Join(wsDir, time.Now().Format("20060102-150405.000000"))
+	err := os.MkdirAll(dir, 0700)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: build logs disabled: %v\n", basename, err)
+		return bl
+	}
+	bl.dir = dir
+
+	builds, _ := os.ReadDir(wsDir)
+	for i := 0; i < len(builds)-buildLogCount; i++ {
+		os.RemoveAll(filepath.Join(wsDir, builds[i].Name()))
+	}
+	return bl
+}
+
+// layerStream returns the stream for the output of the commands of the layer.
+func (bl *buildLog) layerStream(layerIdx int, layer *project.Layer) runtime.Stream {
+
+	stream := bl.rb.StreamWriter()
+	if bl.file != nil {
+		bl.file.Close()
+		bl.file = nil
+	}
+	if bl.dir == "" {
+		return stream
+	}
+
+	name := fmt.Sprintf("%02d-%s.log", layerIdx, layer.Name)
+	file, err := os.Create(filepath.Join(bl.dir, name))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: failed to create build log: %v\n", basename, err)
+		return stream
+	}
+	bl.file = file
+	bl.layers++
+
+	stream.Stdout = io.MultiWriter(stream.Stdout, file)
+	stream.Stderr = io.MultiWriter(stream.Stderr, file)
+	return stream
+}
+
+// close closes the log file and records the generation of a successful build. The log
+// directory is removed if no layer was built.
+func (bl *buildLog) close(ws *project.Workspace, success bool) {
+
+	if bl.file != nil {
+		bl.file.Close()
+		bl.file = nil
+	}
+	if bl.dir != "" && bl.layers == 0 {
+		os.RemoveAll(bl.dir)
+	} else if bl.dir != "" && success {
+		gen := ws.ConfigHash()
+		os.WriteFile(filepath.Join(bl.dir, buildLogGeneration),
+			[]byte(hex.EncodeToString(gen[:])+"\n"), 0600)
+	}
+}
+
+// printTail prints the last lines of the output of the failed command.
+func (bl *buildLog) printTail() {
+
+	bl.rb.Flush()
+	line := make([]byte, outputLineLength)
+	fmt.Printf("Output:\n")
+	for n, err := bl.rb.Read(line); err != io.EOF; n, err = bl.rb.Read(line) {
+		fmt.Printf(" > %v\n", string(line[:n]))
+	}
+	if bl.file != nil {
+		fmt.Printf("Full log: %s\n", bl.file.Name())
+	}
+}
+
+var logsCmd = &cobra.Command{
+	Use:   "logs",
+	Short: "Show logs",
+	Args:  cobra.MinimumNArgs(1),
+}
+
+var logsBuildCmd = &cobra.Command{
+	Use:   "build [layer]",
+	Short: "Show the build logs of the workspace",
+	Long: `
+Show the output of the commands of all layers or the provided layer of the last
+build of the workspace, or of the build with the provided generation. The logs
+of the last builds of each workspace are kept in the logs directory of the cne
+state directory, which can be changed with the setting StateDir.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: logsBuildRunE,
+}
+
+var logsBuildWorkspace string
+var logsBuildGeneration string
+var logsBuildList bool
+
+func logsBuildRunE(cmd *cobra.Command, args []string) error {
+
+	prj, err := readProject()
+	if err != nil {
+		return err
+	}
+
+	var ws *project.Workspace
+	if logsBuildWorkspace != "" {
+		ws, err = prj.Workspace(logsBuildWorkspace)
+	} else {
+		ws, err = prj.CurrentWorkspace()
+	}
+	if err != nil {
+		return err
+	}
+
+	wsDir := workspaceLogDir(ws)
+	builds, err := os.ReadDir(wsDir)
+	if err != nil && !os.IsNotExist(err) {
+		return errdefs.SystemError(err, "failed to read build logs")
+	}
+
+	// find the last build or the build with the generation
+	type buildInfo struct {
+		Build      string
+		Generation string
+	}
+	var buildList []buildInfo
+	for i := len(builds) - 1; i >= 0; i-- {
+		gen, _ := os.ReadFile(filepath.Join(wsDir, builds[i].Name(), buildLogGeneration))
+		buildList = append(buildList, buildInfo{
+			Build:      builds[i].Name(),
+			Generation: strings.TrimSpace(string(gen)),
+		})
+	}
+	if logsBuildList {
+		printList(buildList, false)
+		return nil
+	}
+
+	build := ""
+	for _, b := range buildList {
+		if logsBuildGeneration == "" ||
+			b.Generation != "" && strings.HasPrefix(b.Generation, logsBuildGeneration) {
+			build = b.Build
+			break
+		}
+	}
+	if build == "" {
+		return errdefs.NotFound("build logs", ws.Name)
+	}
+
+	dir := filepath.Join(wsDir, build)
+	logs, err := filepath.Glob(filepath.Join(dir, "*.log"))
+	if err != nil {
+		return errdefs.SystemError(err, "failed to read build logs")
+	}
+	sort.Strings(logs)
+
+	found := false
+	for _, log := range logs {
+		// log files are named <index>-<layer>.log
+		name := strings.TrimSuffix(filepath.Base(log), ".log")
+		idxStr, layerName, _ := strings.Cut(name, "-")
+		if len(args) > 0 && args[0] != layerName {
+			idx, err := strconv.Atoi(idxStr)
+			argIdx, argErr := strconv.Atoi(args[0])
+			if err != nil || argErr != nil || idx != argIdx {
+				continue
+			}
+		}
+		found = true
+
+		content, err := os.ReadFile(log)
+		if err != nil {
+			return errdefs.SystemError(err, "failed to read build log")
+		}
+		if len(args) == 0 {
+			fmt.Printf("==> %s <==\n", layerName)
+		}
+		os.Stdout.Write(content)
+	}
+	if !found && len(args) > 0 {
+		return errdefs.NotFound("build log", args[0])
+	}
+
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(logsCmd)
+	logsCmd.AddCommand(logsBuildCmd)
+	logsBuildCmd.Flags().StringVarP(
+		&logsBuildWorkspace, "workspace", "w", "", "Name of the workspace")
+	logsBuildCmd.Flags().StringVar(
+		&logsBuildGeneration, "generation", "", "Generation of the build")
+	logsBuildCmd.Flags().BoolVar(
+		&logsBuildList, "list", false, "List the builds with logs")
+}
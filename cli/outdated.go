@@ -0,0 +1,152 @@
+package cli
+
+import (
+	"context"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/czankel/cne/project"
+	"github.com/czankel/cne/runtime"
+)
+
+var outdatedCmd = &cobra.Command{
+	Use:   "outdated",
+	Short: "Check the origin images of the workspaces for updates",
+	Long: `
+Compare the digests the origin images of the workspaces are pinned to with the
+digests of the images in the registry. Use 'pull' to update the image of a
+workspace with the update strategy "manual". Workspaces with the strategy
+"auto" are updated when the workspace is built, and workspaces with the
+strategy "never" or an origin with a digest are never updated.`,
+	Args: cobra.NoArgs,
+	RunE: outdatedRunE,
+}
+
+var outdatedWorkspace string
+
+// Status of the origin image of a workspace
+const (
+	outdatedUpToDate = "up-to-date"
+	outdatedOutdated = "outdated"
+	outdatedUnpinned = "unpinned"
+	outdatedFixed    = "fixed"
+	outdatedNoImage  = "no image"
+)
+
+// originStatus returns the status of the origin image of the workspace for the provided digest
+// of the image in the registry.
+func originStatus(ws *project.Workspace, latest string) string {
+
+	env := &ws.Environment
+	switch {
+	case env.Origin == "":
+		return outdatedNoImage
+	case strings.Contains(env.Origin, "@"):
+		return outdatedFixed
+	case env.OriginDigest == "":
+		return outdatedUnpinned
+	case env.OriginDigest != latest:
+		return outdatedOutdated
+	}
+	return outdatedUpToDate
+}
+
+// shortDigest returns the abbreviated encoded part of the digest.
+func shortDigest(dgst string) string {
+
+	if i := strings.Index(dgst, ":"); i >= 0 {
+		dgst = dgst[i+1:]
+	}
+	if len(dgst) > displayHashLength {
+		dgst = dgst[:displayHashLength]
+	}
+	return dgst
+}
+
+func outdatedRunE(cmd *cobra.Command, args []string) error {
+
+	runCfg, err := conf.GetRuntime()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	run, err := runtime.Open(ctx, runCfg)
+	if err != nil {
+		return err
+	}
+	defer run.Close()
+	ctx = run.WithNamespace(ctx, runCfg.Namespace)
+
+	prj, err := readProject()
+	if err != nil {
+		return err
+	}
+
+	wss := prj.Workspaces
+	if outdatedWorkspace != "" {
+		ws, err := prj.Workspace(outdatedWorkspace)
+		if err != nil {
+			return err
+		}
+		wss = []project.Workspace{*ws}
+	}
+
+	type outdatedItem struct {
+		Workspace string
+		Origin    string
+		Update    string
+		Pinned    string
+		Latest    string
+		Status    string
+	}
+	outdatedList := []outdatedItem{}
+
+	// resolve each origin only once
+	resolved := make(map[string]string)
+	for i := range wss {
+		ws := &wss[i]
+		env := &ws.Environment
+
+		latest := ""
+		if env.Origin != "" && !strings.Contains(env.Origin, "@") {
+			var ok bool
+			if latest, ok = resolved[env.Origin]; !ok {
+				dgst, err := resolveImage(ctx, run, env.Origin)
+				if err != nil {
+					return err
+				}
+				latest = dgst.String()
+				resolved[env.Origin] = latest
+			}
+		}
+
+		update := env.Update
+		if update == "" {
+			update = project.UpdateManual
+		}
+		pinned := env.OriginDigest
+		if outputFormat == outputText {
+			pinned = shortDigest(pinned)
+			latest = shortDigest(latest)
+		}
+		outdatedList = append(outdatedList, outdatedItem{
+			Workspace: ws.Name,
+			Origin:    env.Origin,
+			Update:    update,
+			Pinned:    pinned,
+			Latest:    latest,
+			Status:    originStatus(ws, resolved[env.Origin]),
+		})
+	}
+	printList(outdatedList, false)
+
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(outdatedCmd)
+	outdatedCmd.Flags().StringVarP(
+		&outdatedWorkspace, "workspace", "w", "", "Name of the workspace")
+}
@@ -0,0 +1,156 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/czankel/cne/errdefs"
+)
+
+// Output formats for the list and show commands
+const (
+	outputText       = ""
+	outputJSON       = "json"
+	outputYAML       = "yaml"
+	outputGoTemplate = "go-template"
+)
+
+var rootOutput string
+
+var outputFormat string
+var outputTemplate *template.Template
+
+// parseOutputFormat validates the output format in the format json, yaml, or
+// go-template=<template>, and sets the output format and template.
+func parseOutputFormat(output string) error {
+
+	format, tmpl, hasTmpl := strings.Cut(output, "=")
+	switch {
+	case format == outputText && !hasTmpl:
+	case format == outputJSON && !hasTmpl:
+	case format == outputYAML && !hasTmpl:
+	case format == outputGoTemplate && hasTmpl:
+		t, err := template.New("output").Parse(tmpl)
+		if err != nil {
+			return errdefs.InvalidArgument("invalid output template: %v", err)
+		}
+		outputTemplate = t
+	default:
+		return errdefs.InvalidArgument(
+			"invalid output format '%s', use json, yaml, or go-template=<template>", output)
+	}
+	outputFormat = format
+	return nil
+}
+
+// outputValue converts the value to a structure of maps, slices, and values for encoding the
+// value in a structured output format. Struct fields with the tag `output:"-"` are skipped.
+func outputValue(elem reflect.Value) interface{} {
+
+	switch elem.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if elem.IsNil() {
+			return nil
+		}
+		return outputValue(elem.Elem())
+
+	case reflect.Struct:
+		if t, ok := elem.Interface().(time.Time); ok {
+			return t.Format(time.RFC3339Nano)
+		}
+		m := make(map[string]interface{})
+		elemType := elem.Type()
+		for i := 0; i < elem.NumField(); i++ {
+			field := elemType.Field(i)
+			if !field.IsExported() || field.Tag.Get("output") == "-" {
+				continue
+			}
+			m[field.Name] = outputValue(elem.Field(i))
+		}
+		return m
+
+	case reflect.Map:
+		m := make(map[string]interface{})
+		for _, k := range elem.MapKeys() {
+			m[fmt.Sprintf("%v", k.Interface())] = outputValue(elem.MapIndex(k))
+		}
+		return m
+
+	case reflect.Slice, reflect.Array:
+		if elem.Kind() == reflect.Slice && elem.IsNil() {
+			return []interface{}{}
+		}
+		s := make([]interface{}, elem.Len())
+		for i := 0; i < elem.Len(); i++ {
+			s[i] = outputValue(elem.Index(i))
+		}
+		return s
+	}
+
+	if elem.CanInterface() {
+		return elem.Interface()
+	}
+	return nil
+}
+
+// printOutput prints the value in the selected structured output format. Templates are
+// applied to each element of a list.
+func printOutput(value interface{}, isList bool) {
+
+	val := outputValue(reflect.ValueOf(value))
+
+	var err error
+	switch outputFormat {
+	case outputJSON:
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(val)
+
+	case outputYAML:
+		enc := yaml.NewEncoder(os.Stdout)
+		enc.SetIndent(2)
+		err = enc.Encode(val)
+		enc.Close()
+
+	case outputGoTemplate:
+		items := []interface{}{val}
+		if m, ok := val.(map[string]interface{}); ok && isList {
+			keys := make([]string, 0, len(m))
+			for k := range m {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			items = items[:0]
+			for _, k := range keys {
+				// provide the key as the name of the element
+				if im, ok := m[k].(map[string]interface{}); ok {
+					if _, ok := im["Name"]; !ok {
+						im["Name"] = k
+					}
+				}
+				items = append(items, m[k])
+			}
+		} else if s, ok := val.([]interface{}); ok && isList {
+			items = s
+		}
+		for _, item := range items {
+			err = outputTemplate.Execute(os.Stdout, item)
+			if err != nil {
+				break
+			}
+			fmt.Println()
+		}
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: failed to print output: %v\n", basename, err)
+	}
+}
@@ -0,0 +1,124 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/term"
+
+	"github.com/czankel/cne/errdefs"
+	"github.com/czankel/cne/runtime"
+)
+
+// Progress modes
+const (
+	progressPlain = "plain"
+	progressTTY   = "tty"
+	progressJSON  = "json"
+)
+
+var rootProgress string
+
+var progressMode string
+
+// statusTitle returns the progress status with the first letter in upper case.
+func statusTitle(status string) string {
+
+	if status == "" {
+		return status
+	}
+	return strings.ToUpper(status[:1]) + status[1:]
+}
+
+// parseProgressMode validates and sets the progress mode. The default mode is tty if stdout
+// is a terminal and plain otherwise.
+func parseProgressMode(mode string) error {
+
+	switch mode {
+	case "":
+		mode = progressPlain
+		if term.IsTerminal(int(os.Stdout.Fd())) {
+			mode = progressTTY
+		}
+	case progressPlain, progressTTY, progressJSON:
+	default:
+		return errdefs.InvalidArgument(
+			"invalid progress mode '%s', use plain, tty, or json", mode)
+	}
+	progressMode = mode
+	return nil
+}
+
+// showProgressPlain prints a line for every change of the status of a job. Running jobs also
+// print a line when the details change, for example, for every executed command.
+func showProgressPlain(progress <-chan []runtime.ProgressStatus) {
+
+	statCached := make(map[string]runtime.ProgressStatus)
+	for statUpdate := range progress {
+		for _, status := range statUpdate {
+			prev, ok := statCached[status.Reference]
+			statCached[status.Reference] = status
+			if ok && prev.Status == status.Status &&
+				(status.Status != runtime.StatusRunning || prev.Details == status.Details) {
+				continue
+			}
+
+			ref := status.Reference
+			if decoded := strings.Index(ref, ":"); decoded > 0 {
+				ref = ref[decoded+1:]
+			}
+			if len(ref) > 12 {
+				ref = ref[:12]
+			}
+
+			line := fmt.Sprintf("[%s] %s", ref, statusTitle(status.Status))
+			if status.Status == runtime.StatusRunning && status.Details != "" {
+				line = fmt.Sprintf("[%s] %s", ref, status.Details)
+			} else if status.Total != 0 && status.Status == runtime.StatusLoading {
+				line = line + " (" + sizeToSIString(status.Total) + ")"
+			}
+			fmt.Println(line)
+		}
+	}
+}
+
+// progressEvent is the progress status encoded in the json progress mode
+type progressEvent struct {
+	Reference string    `json:"reference"`
+	Status    string    `json:"status"`
+	Offset    int64     `json:"offset"`
+	Total     int64     `json:"total"`
+	Details   string    `json:"details,omitempty"`
+	StartedAt time.Time `json:"startedAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// showProgressJSON prints a newline-delimited json event for every change of a job.
+func showProgressJSON(progress <-chan []runtime.ProgressStatus) {
+
+	enc := json.NewEncoder(os.Stdout)
+	statCached := make(map[string]runtime.ProgressStatus)
+	for statUpdate := range progress {
+		for _, status := range statUpdate {
+			prev, ok := statCached[status.Reference]
+			statCached[status.Reference] = status
+			if ok && prev.Status == status.Status && prev.Offset == status.Offset &&
+				prev.Total == status.Total && prev.Details == status.Details {
+				continue
+			}
+
+			enc.Encode(progressEvent{
+				Reference: status.Reference,
+				Status:    status.Status,
+				Offset:    status.Offset,
+				Total:     status.Total,
+				Details:   status.Details,
+				StartedAt: status.StartedAt,
+				UpdatedAt: status.UpdatedAt,
+			})
+		}
+	}
+}
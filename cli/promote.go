@@ -0,0 +1,78 @@
+package cli
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/czankel/cne/errdefs"
+	"github.com/czankel/cne/project"
+)
+
+var promoteCmd = &cobra.Command{
+	Use:   "promote [layer...]",
+	Short: "Copy layers to the following workspace",
+	Long: `
+Copy the provided layers, or all layers, of the current workspace to the
+following workspace in the project, for example, from a development to a
+test workspace. Layers with the same name are replaced in the following
+workspace, other layers are appended.`,
+	RunE: promoteRunE,
+}
+
+var promoteWorkspace string
+var promoteTo string
+
+func promoteRunE(cmd *cobra.Command, args []string) error {
+
+	prj, err := loadProject()
+	if err != nil {
+		return err
+	}
+
+	var from *project.Workspace
+	if promoteWorkspace != "" {
+		from, err = prj.Workspace(promoteWorkspace)
+	} else {
+		from, err = prj.CurrentWorkspace()
+	}
+	if err != nil {
+		return err
+	}
+
+	var to *project.Workspace
+	if promoteTo != "" {
+		to, err = prj.Workspace(promoteTo)
+		if err != nil {
+			return err
+		}
+	} else {
+		for i, ws := range prj.Workspaces {
+			if ws.Name == from.Name && i+1 < len(prj.Workspaces) {
+				to = &prj.Workspaces[i+1]
+				break
+			}
+		}
+		if to == nil {
+			return errdefs.InvalidArgument(
+				"workspace '%s' is the last workspace", from.Name)
+		}
+	}
+
+	if to.Name == from.Name {
+		return errdefs.InvalidArgument("cannot promote layers to the same workspace")
+	}
+
+	_, err = prj.PromoteLayers(from, to, args)
+	if err != nil {
+		return err
+	}
+
+	return prj.Write()
+}
+
+func init() {
+	rootCmd.AddCommand(promoteCmd)
+	promoteCmd.Flags().StringVarP(
+		&promoteWorkspace, "workspace", "w", "", "Promote the layers of this workspace")
+	promoteCmd.Flags().StringVar(
+		&promoteTo, "to", "", "Promote the layers to this workspace")
+}
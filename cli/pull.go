@@ -2,19 +2,47 @@ package cli
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
 	"sync"
 
+	digest "github.com/opencontainers/go-digest"
 	"github.com/spf13/cobra"
 
+	"github.com/czankel/cne/errdefs"
+	"github.com/czankel/cne/project"
 	"github.com/czankel/cne/runtime"
 )
 
-func pullImage(ctx context.Context, run runtime.Runtime, imgName string) (runtime.Image, error) {
+// registryCredentials returns the credentials of the registry host for the current user.
+func registryCredentials(host string) (string, string, error) {
+	return conf.Credentials(&user, host)
+}
+
+// configureRegistries sets the configuration and credentials of the registries in the runtime.
+func configureRegistries(run runtime.Runtime) {
+	run.SetRegistries(conf.Registry)
+	run.SetCredentials(registryCredentials)
+}
+
+// resolveImage returns the digest of the image in the registry.
+func resolveImage(ctx context.Context,
+	run runtime.Runtime, imgName string) (digest.Digest, error) {
+
+	configureRegistries(run)
+	return run.ResolveImage(ctx, imgName)
+}
+
+func pullImage(ctx context.Context,
+	run runtime.Runtime, imgName, platform string) (runtime.Image, error) {
 
 	imgName, err := conf.FullImageName(imgName)
 	if err != nil {
 		return nil, err
 	}
+	configureRegistries(run)
 
 	progress := make(chan []runtime.ProgressStatus)
 	var wg sync.WaitGroup
@@ -26,21 +54,126 @@ func pullImage(ctx context.Context, run runtime.Runtime, imgName string) (runtim
 		showProgress(progress)
 	}()
 
-	return run.PullImage(ctx, imgName, progress)
+	return run.PullImage(ctx, imgName, platform, progress)
+}
+
+// localOriginImage returns the local image of the origin of the workspace. The image must match
+// the digest the workspace is pinned to, if the workspace is pinned.
+func localOriginImage(ctx context.Context,
+	run runtime.Runtime, ws *project.Workspace) (runtime.Image, error) {
+
+	env := &ws.Environment
+	img, err := run.GetImage(ctx, env.Origin, env.Platform)
+	if err == nil && (env.OriginDigest == "" || img.RepoDigest().String() == env.OriginDigest) {
+		return img, nil
+	}
+	if err != nil && !errors.Is(err, errdefs.ErrNotFound) {
+		return nil, err
+	}
+	return run.GetImage(ctx, ws.OriginReference(), env.Platform)
+}
+
+// pullOriginImage returns the image of the origin of the workspace and pulls the image if it
+// doesn't exist locally. Workspaces that aren't pinned yet are pinned to the digest of the image.
+// Workspaces with the update strategy "auto" are updated to the latest image in the registry.
+func pullOriginImage(ctx context.Context,
+	run runtime.Runtime, ws *project.Workspace) (runtime.Image, error) {
+
+	env := &ws.Environment
+	fixed := strings.Contains(env.Origin, "@")
+	if env.Update == project.UpdateAuto && !fixed {
+		dgst, err := resolveImage(ctx, run, env.Origin)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: cannot check image '%s' for updates: %v\n",
+				basename, env.Origin, err)
+		} else if dgst.String() != env.OriginDigest {
+			img, err := pullImage(ctx, run, env.Origin, env.Platform)
+			if err != nil {
+				return nil, err
+			}
+			env.OriginDigest = img.RepoDigest().String()
+			return img, nil
+		}
+	}
+
+	img, err := localOriginImage(ctx, run, ws)
+	if err != nil && errors.Is(err, errdefs.ErrNotFound) {
+		img, err = pullImage(ctx, run, ws.OriginReference(), env.Platform)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if env.OriginDigest == "" && !fixed {
+		env.OriginDigest = img.RepoDigest().String()
+	}
+	return img, nil
 }
 
 var pullCmd = &cobra.Command{
-	Use:   "pull [registry]package[:tag|@digest]",
+	Use:   "pull [[registry]package[:tag|@digest]]",
 	Short: "Pull an image from a registry",
 	Long: `
 Pull an image from a registry to the local system.
 REGISTRY can be one of the configured registries or directly
 specify the domain and repository. If omitted, the default
-registry is used.`,
-	Args: cobra.ExactArgs(1),
+registry is used.
+
+Without an image, pull the origin image of the current or the
+provided workspace for the platform of the workspace and pin the
+workspace to the digest of the pulled image. Workspaces with the
+update strategy "never" stay pinned to the current digest.`,
+	Args: cobra.MaximumNArgs(1),
 	RunE: pullImageRunE,
 }
 
+var pullWorkspace string
+var pullPlatform string
+
+// pullWorkspaceImage pulls the origin image of the workspace and updates the digest the
+// workspace is pinned to.
+func pullWorkspaceImage(ctx context.Context, run runtime.Runtime) error {
+
+	prj, err := loadProject()
+	if err != nil {
+		return err
+	}
+
+	var ws *project.Workspace
+	if pullWorkspace != "" {
+		ws, err = prj.Workspace(pullWorkspace)
+	} else {
+		ws, err = prj.CurrentWorkspace()
+	}
+	if err != nil {
+		return err
+	}
+
+	env := &ws.Environment
+	if env.Origin == "" {
+		return errdefs.InvalidArgument("Workspace has no image defined")
+	}
+	if env.Update == project.UpdateNever || strings.Contains(env.Origin, "@") {
+		_, err = pullOriginImage(ctx, run, ws)
+		if err != nil {
+			return err
+		}
+		return prj.Write()
+	}
+
+	img, err := pullImage(ctx, run, env.Origin, env.Platform)
+	if err != nil {
+		return err
+	}
+
+	dgst := img.RepoDigest().String()
+	if dgst != env.OriginDigest {
+		fmt.Printf("Workspace '%s' pinned to %s\n", ws.Name, dgst)
+	}
+	prj.UpdateWorkspace(ws, env.Origin, dgst)
+
+	return prj.Write()
+}
+
 func pullImageRunE(cmd *cobra.Command, args []string) error {
 
 	runCfg, err := conf.GetRuntime()
@@ -56,11 +189,25 @@ func pullImageRunE(cmd *cobra.Command, args []string) error {
 	defer run.Close()
 	ctx = run.WithNamespace(ctx, runCfg.Namespace)
 
-	_, err = pullImage(ctx, run, args[0])
+	if len(args) == 0 {
+		if pullPlatform != "" {
+			return errdefs.InvalidArgument("cannot use --platform without an image")
+		}
+		return pullWorkspaceImage(ctx, run)
+	}
+	if pullWorkspace != "" {
+		return errdefs.InvalidArgument("cannot use --workspace with an image")
+	}
+
+	_, err = pullImage(ctx, run, args[0], pullPlatform)
 
 	return err
 }
 
 func init() {
 	rootCmd.AddCommand(pullCmd)
+	pullCmd.Flags().StringVarP(
+		&pullWorkspace, "workspace", "w", "", "Name of the workspace")
+	pullCmd.Flags().StringVar(
+		&pullPlatform, "platform", "", "Platform of the image, for example, linux/arm64")
 }
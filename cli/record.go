@@ -0,0 +1,232 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/containerd/console"
+
+	"github.com/czankel/cne/container"
+	"github.com/czankel/cne/errdefs"
+	"github.com/czankel/cne/project"
+	"github.com/czankel/cne/runtime"
+)
+
+const recordShell = "/bin/bash"
+
+var recordCmd = &cobra.Command{
+	Use:   "record",
+	Short: "Record the commands of an interactive shell in a layer",
+	Long: `
+Start an interactive shell in the build container of the provided layer and
+record all command lines. When the shell exits, select the commands to add to
+the layer. The selected command lines are added as a single bash script, so
+that changes to the working directory and environment variables are preserved
+when the layer is rebuilt. If all commands are kept, the changes made in the
+shell are added to the layer snapshot. Otherwise, the layer will be rebuilt
+from its commands. The shell requires bash in the container.`,
+	Args: cobra.NoArgs,
+	RunE: recordRunE,
+}
+
+var recordLayer string
+var recordWorkspace string
+
+// recordSession starts an interactive shell in the container and returns the command lines
+// entered by the user.
+func recordSession(ctx context.Context, ctr runtime.Container, layerName string) ([]string, error) {
+
+	// the home directory is also mounted in the container
+	dir, err := os.MkdirTemp(user.HomeDir, ".cne-record-")
+	if err != nil {
+		return nil, errdefs.SystemError(err, "failed to create temporary directory")
+	}
+	defer os.RemoveAll(dir)
+
+	histFile := filepath.Join(dir, "history")
+	rcFile := filepath.Join(dir, "bashrc")
+	rc := "HISTFILE=" + histFile + "\n" +
+		"HISTCONTROL=\n" +
+		"HISTIGNORE=\n" +
+		"PROMPT_COMMAND='history -a'\n" +
+		"PS1='(record:" + layerName + ") \\w\\$ '\n"
+	err = os.WriteFile(rcFile, []byte(rc), 0644)
+	if err != nil {
+		return nil, errdefs.SystemError(err, "failed to create shell configuration")
+	}
+
+	con := console.Current()
+	err = con.SetRaw()
+	if err != nil {
+		return nil, err
+	}
+
+	stream := runtime.Stream{
+		Stdin:    os.Stdin,
+		Stdout:   os.Stdout,
+		Stderr:   os.Stderr,
+		Terminal: true,
+	}
+	_, err = container.BuildExec(ctx, ctr, &user, stream,
+		[]string{recordShell, "--rcfile", rcFile, "-i"}, []string{})
+	con.Reset()
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(histFile)
+	if err != nil && os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errdefs.SystemError(err, "failed to read recorded commands")
+	}
+	defer file.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" && line != "exit" {
+			lines = append(lines, line)
+		}
+	}
+	return lines, scanner.Err()
+}
+
+// recordCommand returns the command for replaying the recorded command lines. The lines are
+// executed as a single script by the shell they were recorded in, so that changes to the
+// working directory and exported variables carry over to the following lines.
+func recordCommand(lines []string) project.Command {
+	return project.Command{
+		Envs: []string{},
+		Args: []string{recordShell, "-c", strings.Join(lines, "\n")},
+	}
+}
+
+func recordRunE(cmd *cobra.Command, args []string) error {
+
+	runCfg, err := conf.GetRuntime()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	run, err := runtime.Open(ctx, runCfg)
+	if err != nil {
+		return err
+	}
+	defer run.Close()
+	ctx = run.WithNamespace(ctx, runCfg.Namespace)
+
+	prj, err := loadProject()
+	if err != nil {
+		return err
+	}
+
+	var ws *project.Workspace
+	if recordWorkspace != "" {
+		ws, err = prj.Workspace(recordWorkspace)
+	} else {
+		ws, err = prj.CurrentWorkspace()
+	}
+	if err != nil {
+		return err
+	}
+
+	layerIdx, layer, err := ws.FindLayer(recordLayer)
+	if err != nil {
+		return err
+	}
+	if layer.Opaque {
+		return errdefs.InvalidArgument("cannot record commands for snapshot layer '%s'",
+			layer.Name)
+	}
+
+	// don't block other commands while recording; writing the project fails with a conflict
+	// if another command changed the project in the meantime
+	unlockProject()
+	ctr, err := buildContainer(ctx, run, ws, layerIdx+1)
+	if err != nil {
+		return err
+	}
+
+	lines, err := recordSession(ctx, ctr, layer.Name)
+	if err != nil {
+		return err
+	}
+	if len(lines) == 0 {
+		fmt.Println("No commands recorded")
+		ctr.Delete(ctx) // discard changes
+		return nil
+	}
+
+	for i, l := range lines {
+		fmt.Printf("%3d  %s\n", i+1, l)
+	}
+	fmt.Printf("Commands to add to layer '%s' (e.g. 1,3-5 or all) [all]: ", layer.Name)
+	reader := bufio.NewReader(os.Stdin)
+	sel, _ := reader.ReadString('\n')
+	if strings.TrimSpace(sel) == "" {
+		sel = "all"
+	}
+	indices, err := parseSelection(sel, len(lines))
+	if err != nil {
+		ctr.Delete(ctx) // discard changes
+		return err
+	}
+
+	err = lockProject()
+	if err == nil {
+		err = prj.CheckConflict()
+	}
+	if err != nil {
+		ctr.Delete(ctx) // discard changes
+		return err
+	}
+
+	var selected []string
+	for _, i := range indices {
+		selected = append(selected, lines[i])
+	}
+	commands := []project.Command{recordCommand(selected)}
+	layer = &ws.Environment.Layers[layerIdx]
+	if err := ws.InsertCommands(layer, "", commands); err != nil {
+		ctr.Delete(ctx)
+		return err
+	}
+	ws.UpdateLayer(layer)
+
+	// the snapshot includes the changes of all commands, so only keep it if all are used
+	if len(indices) == len(lines) {
+		snap, err := ctr.Amend(ctx)
+		if err != nil && !errors.Is(err, errdefs.ErrAlreadyExists) {
+			ctr.Delete(ctx) // delete the container and active snapshot
+			return err
+		}
+		if snap != nil {
+			layer.Digest = snap.Name()
+		}
+	} else {
+		ctr.Delete(ctx)
+		fmt.Printf("Layer '%s' will be rebuilt\n", layer.Name)
+	}
+
+	return prj.Write()
+}
+
+func init() {
+	rootCmd.AddCommand(recordCmd)
+	recordCmd.Flags().StringVarP(
+		&recordLayer, "layer", "l", "", "Add the recorded commands to this layer")
+	recordCmd.Flags().StringVarP(
+		&recordWorkspace, "workspace", "w", "", "Name of the workspace")
+	recordCmd.MarkFlagRequired("layer")
+}
@@ -93,13 +93,13 @@ func (rb *RingBuffer) Read(p []byte) (int, error) {
 		return 0, io.EOF
 	}
 
-	copy(p, rb.lines[rb.firstLine])
+	n := copy(p, rb.lines[rb.firstLine])
 	rb.firstLine++
 	if rb.firstLine >= len(rb.lines) {
 		rb.firstLine = 0
 	}
 
-	return len(p), nil
+	return n, nil
 }
 
 // Flush flushes all line buffers.
@@ -0,0 +1,104 @@
+package cli
+
+import (
+	"context"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/containerd/console"
+
+	"github.com/czankel/cne/container"
+	"github.com/czankel/cne/project"
+	"github.com/czankel/cne/runtime"
+)
+
+var shellCmd = &cobra.Command{
+	Use:   "shell",
+	Short: "Start an interactive shell in the container environment",
+	Long: `
+Start the shell of the current user as a login shell in the current directory
+inside the container environment. The environment variables CNE_PROJECT and
+CNE_WORKSPACE identify the project and workspace of the shell.`,
+	Args: cobra.NoArgs,
+	RunE: shellRunE,
+}
+
+var shellWorkspace string
+
+func shellRunE(cmd *cobra.Command, args []string) error {
+
+	runCfg, err := conf.GetRuntime()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	run, err := runtime.Open(ctx, runCfg)
+	if err != nil {
+		return err
+	}
+	defer run.Close()
+	ctx = run.WithNamespace(ctx, runCfg.Namespace)
+
+	prj, err := loadProject()
+	if err != nil {
+		return err
+	}
+
+	var ws *project.Workspace
+	if shellWorkspace == "" {
+		ws, err = prj.CurrentWorkspace()
+	} else {
+		ws, err = prj.Workspace(shellWorkspace)
+	}
+	if err != nil {
+		return err
+	}
+
+	ctr, err := getExecContainer(ctx, run, prj, ws)
+	if err != nil {
+		return err
+	}
+	unlockProject()
+
+	con := console.Current()
+	defer con.Reset()
+
+	err = con.SetRaw()
+	if err != nil {
+		return err
+	}
+
+	stream := runtime.Stream{
+		Stdin:    os.Stdin,
+		Stdout:   os.Stdout,
+		Stderr:   os.Stderr,
+		Terminal: true,
+	}
+
+	// debian_chroot is used by the default prompt of Debian-based images
+	envs := []string{
+		"CNE_PROJECT=" + prj.Name,
+		"CNE_WORKSPACE=" + ws.Name,
+		"debian_chroot=cne:" + ws.Name,
+	}
+
+	code, err := container.Shell(ctx, ctr, &user, stream, envs)
+	if err != nil {
+		return err
+	}
+	if code != 0 {
+		con.Reset()
+		run.Close()
+		os.Exit(int(code))
+	}
+
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(shellCmd)
+	shellCmd.Flags().StringVarP(&shellWorkspace, "workspace", "w", "",
+		"Start the shell in this workspace instead of the current workspace")
+}
@@ -4,9 +4,11 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/spf13/cobra"
 
+	"github.com/czankel/cne/config"
 	"github.com/czankel/cne/errdefs"
 	"github.com/czankel/cne/project"
 	"github.com/czankel/cne/runtime"
@@ -29,7 +31,9 @@ var showContextCmd = &cobra.Command{
 
 func showContextRunE(cmd *cobra.Command, args []string) error {
 
-	fmt.Printf("conf %v\n", conf.Settings)
+	if outputFormat == outputText {
+		fmt.Printf("conf %v\n", conf.Settings)
+	}
 	entry := conf.Settings.Context
 	if len(args) > 0 {
 		entry = args[0]
@@ -50,6 +54,8 @@ var showImageCmd = &cobra.Command{
 	Args:  cobra.RangeArgs(0, 1),
 }
 
+var showImagePlatform string
+
 type OS struct {
 	Name    string
 	Version string
@@ -75,10 +81,11 @@ func showImageRunE(cmd *cobra.Command, args []string) error {
 	ctx = run.WithNamespace(ctx, runCfg.Namespace)
 
 	var imgName string
+	platform := showImagePlatform
 	if len(args) > 0 {
 		imgName = args[0]
 	} else {
-		prj, err := loadProject()
+		prj, err := readProject()
 		if err != nil {
 			return err
 		}
@@ -88,6 +95,9 @@ func showImageRunE(cmd *cobra.Command, args []string) error {
 			return err
 		}
 		imgName = ws.Environment.Origin
+		if platform == "" {
+			platform = ws.Environment.Platform
+		}
 	}
 
 	imgName, err = conf.FullImageName(imgName)
@@ -95,9 +105,9 @@ func showImageRunE(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	img, err := run.GetImage(ctx, imgName)
+	img, err := run.GetImage(ctx, imgName, platform)
 	if err != nil && errors.Is(err, errdefs.ErrNotFound) {
-		img, err = pullImage(ctx, run, imgName)
+		img, err = pullImage(ctx, run, imgName, platform)
 	}
 	if err != nil {
 		return err
@@ -120,15 +130,17 @@ func showImageRunE(cmd *cobra.Command, args []string) error {
 	}
 
 	image := struct {
-		Name   string
-		Size   int64
-		OS     string
-		RootFS []string
+		Name     string
+		Size     int64
+		OS       string
+		Platform string
+		RootFS   []string
 	}{
-		Name:   img.Name(),
-		Size:   img.Size(),
-		OS:     fullName,
-		RootFS: rootfs,
+		Name:     img.Name(),
+		Size:     img.Size(),
+		OS:       fullName,
+		Platform: img.Platform(),
+		RootFS:   rootfs,
 	}
 
 	printValue("Field", "Value", "", image)
@@ -145,7 +157,7 @@ var showProjectCmd = &cobra.Command{
 
 func showProjectRunE(cmd *cobra.Command, args []string) error {
 
-	prj, err := loadProject()
+	prj, err := readProject()
 	if err != nil {
 		return err
 	}
@@ -172,8 +184,19 @@ func showRegistryRunE(cmd *cobra.Command, args []string) error {
 		}
 		entry = cfgCtx.Registry
 	}
-	_, val, err := conf.GetAllByName("registry/" + entry)
+	path, val, err := conf.GetAllByName("registry/" + entry)
 	if err == nil {
+		switch v := val.(type) {
+		case config.Registry:
+			if v.Token != "" {
+				v.Token = maskedToken
+			}
+			val = v
+		case string:
+			if v != "" && strings.HasSuffix(strings.ToLower(path), "/token") {
+				val = maskedToken
+			}
+		}
 		printValue("Configuration", "Value", "", val)
 	}
 	return nil
@@ -218,7 +241,7 @@ var showWorkspaceCmd = &cobra.Command{
 
 func showWorkspaceRunE(cmd *cobra.Command, args []string) error {
 
-	prj, err := loadProject()
+	prj, err := readProject()
 	if err != nil {
 		return err
 	}
@@ -251,6 +274,8 @@ func init() {
 		&showUserConfig, "user", "", false, "Show only user configurations")
 
 	showCmd.AddCommand(showImageCmd)
+	showImageCmd.Flags().StringVar(
+		&showImagePlatform, "platform", "", "Platform of the image, for example, linux/arm64")
 	showCmd.AddCommand(showProjectCmd)
 
 	showCmd.AddCommand(showRegistryCmd)
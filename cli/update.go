@@ -3,12 +3,16 @@ package cli
 import (
 	"context"
 	"errors"
+	"fmt"
+	"os"
+	"strconv"
 	"strings"
 
 	"github.com/spf13/cobra"
 
 	"github.com/czankel/cne/container"
 	"github.com/czankel/cne/errdefs"
+	"github.com/czankel/cne/project"
 	"github.com/czankel/cne/runtime"
 )
 
@@ -16,7 +20,7 @@ import (
 func updateContainerOptions(options map[string]string) error {
 
 	// TODO: create custom boilerplate function
-	prj, err := loadProject()
+	prj, err := readProject()
 	if err != nil {
 		return err
 	}
@@ -93,6 +97,117 @@ func updateWorkspaceRunE(cmd *cobra.Command, args []string) error {
 
 var updateRenameEntry string
 
+var updateLayerCmd = &cobra.Command{
+	Use:     "layer name",
+	Short:   "Update a layer of a workspace",
+	Aliases: []string{"l"},
+	Args:    cobra.ExactArgs(1),
+	RunE:    updateLayerRunE,
+}
+
+var updateLayerWorkspace string
+var updateLayerMoveTo string
+var updateLayerRename string
+var updateLayerDisable bool
+var updateLayerEnable bool
+var updateLayerReplayable bool
+
+func updateLayerRunE(cmd *cobra.Command, args []string) error {
+
+	if updateLayerDisable && updateLayerEnable {
+		return errdefs.InvalidArgument("cannot enable and disable the layer")
+	}
+
+	prj, err := loadProject()
+	if err != nil {
+		return err
+	}
+
+	var ws *project.Workspace
+	if updateLayerWorkspace != "" {
+		ws, err = prj.Workspace(updateLayerWorkspace)
+	} else {
+		ws, err = prj.CurrentWorkspace()
+	}
+	if err != nil {
+		return err
+	}
+
+	name := args[0]
+	if updateLayerRename != "" {
+		if err := ws.RenameLayer(name, updateLayerRename); err != nil {
+			return err
+		}
+		name = updateLayerRename
+	}
+
+	if updateLayerMoveTo != "" {
+		if err := ws.MoveLayer(name, updateLayerMoveTo); err != nil {
+			return err
+		}
+	}
+
+	if updateLayerDisable || updateLayerEnable {
+		_, layer, err := ws.FindLayer(name)
+		if err != nil {
+			return err
+		}
+		ws.DisableLayer(layer, updateLayerDisable)
+	}
+
+	if updateLayerReplayable {
+		_, layer, err := ws.FindLayer(name)
+		if err != nil {
+			return err
+		}
+		if layer.Opaque {
+			layer.Opaque = false
+			ws.UpdateLayer(layer)
+		}
+	}
+
+	return prj.Write()
+}
+
+var updateCommandCmd = &cobra.Command{
+	Use:     "command index|name",
+	Short:   "Update a command of a layer",
+	Aliases: []string{"cmd"},
+	Args:    cobra.ExactArgs(1),
+	RunE:    updateCommandRunE,
+}
+
+var updateCommandWorkspace string
+var updateCommandLayer string
+var updateCommandDisable bool
+var updateCommandEnable bool
+
+func updateCommandRunE(cmd *cobra.Command, args []string) error {
+
+	if updateCommandDisable && updateCommandEnable {
+		return errdefs.InvalidArgument("cannot enable and disable the command")
+	}
+
+	prj, err := loadProject()
+	if err != nil {
+		return err
+	}
+
+	ws, layer, err := getLayer(prj, updateCommandWorkspace, updateCommandLayer)
+	if err != nil {
+		return err
+	}
+
+	if updateCommandDisable || updateCommandEnable {
+		err = ws.DisableCommand(layer, args[0], updateCommandDisable)
+		if err != nil {
+			return err
+		}
+	}
+
+	return prj.Write()
+}
+
 var updateContextCmd = &cobra.Command{
 	Use:   "context [context]",
 	Short: "Update context configurations",
@@ -192,11 +307,14 @@ var updateRegistryCmd = &cobra.Command{
 	Use:   "registry [name]",
 	Short: "Update registry configurations",
 	Args:  cobra.RangeArgs(0, 1),
-	RunE:  updateContextRunE,
+	RunE:  updateRegistryRunE,
 }
 
 var updateRegistryDomain string
 var updateRegistryRepoName string
+var updateRegistryMirrors []string
+var updateRegistryInsecure bool
+var updateRegistryCAFile string
 
 func updateRegistryRunE(cmd *cobra.Command, args []string) error {
 
@@ -208,12 +326,17 @@ func updateRegistryRunE(cmd *cobra.Command, args []string) error {
 	name := ""
 	if len(args) > 0 {
 		name = args[0]
+	} else {
+		cfgCtx, _, err := conf.GetContext()
+		if err != nil {
+			return err
+		}
+		name = cfgCtx.Registry
 	}
 
-	// find registry in merged configuration
-	confReg, err := conf.GetRegistry(name)
-	if err != nil {
-		return err
+	confReg, found := tempConf.Registry[name]
+	if !found {
+		return errdefs.NotFound("registry", name)
 	}
 
 	if updateRenameEntry != "" {
@@ -243,6 +366,23 @@ func updateRegistryRunE(cmd *cobra.Command, args []string) error {
 		confReg.RepoName = updateRegistryRepoName
 		changes = append(changes, changeInfo{"RepoName", orig, updateRegistryRepoName})
 	}
+	if err == nil && cmd.Flags().Changed("mirror") {
+		orig := strings.Join(confReg.Mirrors, ",")
+		confReg.Mirrors = updateRegistryMirrors
+		changes = append(changes,
+			changeInfo{"Mirrors", orig, strings.Join(updateRegistryMirrors, ",")})
+	}
+	if err == nil && cmd.Flags().Changed("insecure") {
+		orig := strconv.FormatBool(confReg.Insecure)
+		confReg.Insecure = updateRegistryInsecure
+		changes = append(changes,
+			changeInfo{"Insecure", orig, strconv.FormatBool(updateRegistryInsecure)})
+	}
+	if err == nil && cmd.Flags().Changed("ca-file") {
+		orig := confReg.CAFile
+		confReg.CAFile = updateRegistryCAFile
+		changes = append(changes, changeInfo{"CAFile", orig, updateRegistryCAFile})
+	}
 
 	err = writeConfig(tempConf)
 	if err != nil {
@@ -333,6 +473,40 @@ var updateProjectCmd = &cobra.Command{
 }
 
 var updateProjectWorkspace string
+var updateProjectMigrate bool
+var updateProjectDryRun bool
+
+// migrateProject writes a project that was migrated when loading it or only shows the changes
+// to the project file for a dry-run.
+func migrateProject(prj *project.Project, dryRun bool) error {
+
+	if prj.FileVersion() == project.FileVersion {
+		fmt.Printf("Project file is up to date (version %s)\n", project.FileVersion)
+		return nil
+	}
+
+	if !dryRun {
+		return prj.Write()
+	}
+
+	oldStr, err := os.ReadFile(prj.Path)
+	if err != nil {
+		return errdefs.SystemError(err, "failed to read project file '%s'", prj.Path)
+	}
+	newStr, err := prj.Marshal()
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Migrate project file from version %s to %s\n",
+		prj.FileVersion(), project.FileVersion)
+	diff := diffLines(strings.Split(strings.TrimRight(string(oldStr), "\n"), "\n"),
+		strings.Split(strings.TrimRight(string(newStr), "\n"), "\n"))
+	for _, l := range diff {
+		fmt.Println(l)
+	}
+	return nil
+}
 
 func updateProjectRunE(cmd *cobra.Command, args []string) error {
 
@@ -341,6 +515,10 @@ func updateProjectRunE(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	if updateProjectMigrate {
+		return migrateProject(prj, updateProjectDryRun)
+	}
+
 	err = prj.SetCurrentWorkspace(updateProjectWorkspace)
 	if err != nil {
 		return err
@@ -352,6 +530,16 @@ func updateProjectRunE(cmd *cobra.Command, args []string) error {
 func init() {
 	rootCmd.AddCommand(updateCmd)
 
+	updateCmd.AddCommand(updateCommandCmd)
+	updateCommandCmd.Flags().StringVarP(
+		&updateCommandWorkspace, "workspace", "w", "", "Name of the workspace")
+	updateCommandCmd.Flags().StringVarP(
+		&updateCommandLayer, "layer", "l", "", "Name or index of the layer")
+	updateCommandCmd.Flags().BoolVar(
+		&updateCommandDisable, "disable", false, "Skip the command when building")
+	updateCommandCmd.Flags().BoolVar(
+		&updateCommandEnable, "enable", false, "Enable a disabled command")
+
 	updateCmd.AddCommand(updateContextCmd)
 	updateContextCmd.Flags().StringVar(
 		&updateContextOptions, "options", "", "Container runtime options")
@@ -366,17 +554,44 @@ func init() {
 	updateContextCmd.Flags().BoolVarP(
 		&configProject, "project", "", false, "Update project configuration")
 
+	updateCmd.AddCommand(updateLayerCmd)
+	updateLayerCmd.Flags().StringVarP(
+		&updateLayerWorkspace, "workspace", "w", "", "Name of the workspace")
+	updateLayerCmd.Flags().StringVar(
+		&updateLayerMoveTo, "move-to", "", "Move the layer before this layer or index")
+	updateLayerCmd.Flags().StringVar(
+		&updateLayerRename, "rename", "", "Rename the layer")
+	updateLayerCmd.Flags().BoolVar(
+		&updateLayerDisable, "disable", false, "Skip the layer when building")
+	updateLayerCmd.Flags().BoolVar(
+		&updateLayerEnable, "enable", false, "Enable a disabled layer")
+	updateLayerCmd.Flags().BoolVar(
+		&updateLayerReplayable, "replayable", false,
+		"Rebuild a snapshot layer from its commands")
+
 	updateCmd.AddCommand(updateProjectCmd)
 	updateProjectCmd.Flags().StringVar(
 		&updateProjectWorkspace, "workspace", "", "Change the current workspace for the project")
 	updateProjectCmd.Flags().StringVar(
 		&updateProjectWorkspace, "ws", "", "Change the current workspace for the project")
+	updateProjectCmd.Flags().BoolVar(
+		&updateProjectMigrate, "migrate", false,
+		"Migrate the project file to the current version")
+	updateProjectCmd.Flags().BoolVar(
+		&updateProjectDryRun, "dry-run", false, "Only show the changes for --migrate")
 
 	updateCmd.AddCommand(updateRegistryCmd)
 	updateRegistryCmd.Flags().StringVar(
 		&updateRegistryDomain, "domain", "", "Change the registry domain address")
 	updateRegistryCmd.Flags().StringVar(
 		&updateRegistryRepoName, "reponame", "", "Change the registry repo-name")
+	updateRegistryCmd.Flags().StringSliceVar(
+		&updateRegistryMirrors, "mirror", []string{}, "Change the registry mirror endpoints")
+	updateRegistryCmd.Flags().BoolVar(
+		&updateRegistryInsecure, "insecure", false,
+		"Skip TLS verification and allow plain HTTP for the registry")
+	updateRegistryCmd.Flags().StringVar(
+		&updateRegistryCAFile, "ca-file", "", "Change the CA bundle for the registry")
 	updateRegistryCmd.Flags().StringVar(
 		&updateRenameEntry, "rename", "", "Rename the entry")
 	updateRegistryCmd.Flags().BoolVarP(
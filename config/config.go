@@ -24,7 +24,9 @@ var CneVersion string
 var contextName string
 
 type Settings struct {
-	Context string `toml:",omitempty"`
+	Context      string `toml:",omitempty"`
+	StateDir     string `toml:",omitempty"` // directory for build logs
+	FailureLines int    `toml:",omitempty"` // output lines shown for failed build commands
 }
 
 type Runtime struct {
@@ -36,6 +38,11 @@ type Runtime struct {
 type Registry struct {
 	Domain   string
 	RepoName string
+	Username string   `toml:",omitempty"` // username for registries requiring authentication
+	Token    string   `toml:",omitempty"` // password or access token for the username
+	Mirrors  []string `toml:",omitempty"` // mirror endpoints tried before the registry
+	Insecure bool     `toml:",omitempty"` // skip TLS verification and allow plain HTTP
+	CAFile   string   `toml:",omitempty"` // path of a CA bundle for verifying the registry
 }
 
 type Context struct {
@@ -316,7 +323,8 @@ func NewDefault() *Config {
 
 	return &Config{
 		Settings: Settings{
-			Context: DefaultContextName,
+			Context:      DefaultContextName,
+			FailureLines: DefaultFailureLines,
 		},
 		Context: map[string]*Context{
 			DefaultContextName: &Context{
@@ -337,6 +345,19 @@ func NewDefault() *Config {
 	}
 }
 
+// GetStateDir returns the directory for the build logs of the user. The default directory is
+// $XDG_STATE_HOME/cne or ~/.local/state/cne.
+func (conf *Config) GetStateDir(usr *User) string {
+
+	if conf.Settings.StateDir != "" {
+		return conf.Settings.StateDir
+	}
+	if dir := os.Getenv("XDG_STATE_HOME"); dir != "" {
+		return dir + "/" + DefaultStateDirName
+	}
+	return usr.HomeDir + "/.local/state/" + DefaultStateDirName
+}
+
 // UpdateProjectConfig updates the configuration from the config file in the project path
 func (conf *Config) UpdateProjectConfig(path string) error {
 
@@ -514,9 +535,26 @@ func (conf *Config) GetAllByName(name string) (string, interface{}, error) {
 	return path, field.Interface(), nil
 }
 
+// hasTokens checks if any registry of the configuration includes a token.
+func (conf *Config) hasTokens() bool {
+
+	for _, reg := range conf.Registry {
+		if reg != nil && reg.Token != "" {
+			return true
+		}
+	}
+	return false
+}
+
 // WriteSystemConfig writes the system configuration to /etc/cneconfig.
+// Registry tokens cannot be stored in the system configuration.
 func (conf *Config) WriteSystemConfig() error {
 
+	if conf.hasTokens() {
+		return errdefs.InvalidArgument(
+			"registry tokens can only be stored in the user configuration")
+	}
+
 	file, err := os.OpenFile(SystemConfigFile, os.O_TRUNC|os.O_RDWR|os.O_CREATE, ConfigFilePerms)
 	if err != nil {
 		return errdefs.SystemError(err, "failed to open configuration file: %s",
@@ -539,6 +577,7 @@ func (conf *Config) WriteSystemConfig() error {
 }
 
 // WriteUserConfig writes the user configuration in the home directory of the current user.
+// The configuration is only readable by the user if it includes registry tokens.
 func (conf *Config) WriteUserConfig() error {
 
 	usr, err := user.Current()
@@ -546,14 +585,24 @@ func (conf *Config) WriteUserConfig() error {
 		return err
 	}
 
+	perms := os.FileMode(ConfigFilePerms)
+	if conf.hasTokens() {
+		perms = ConfigSecretFilePerms
+	}
+
 	path := usr.HomeDir + "/" + UserConfigFile
-	file, err := os.OpenFile(path, os.O_TRUNC|os.O_RDWR|os.O_CREATE, ConfigFilePerms)
+	file, err := os.OpenFile(path, os.O_TRUNC|os.O_RDWR|os.O_CREATE, perms)
 	if err != nil {
 		return errdefs.SystemError(err, "failed to write configuration file '%s'", path)
 	}
 	defer file.Close()
 	defer file.Sync()
 
+	// the permissions of an existing file are not changed by opening it
+	if err = file.Chmod(perms); err != nil {
+		return errdefs.SystemError(err, "failed to update permissions for '%s'", path)
+	}
+
 	euid := os.Geteuid()
 	uid := os.Getuid()
 	if euid != uid {
@@ -572,8 +621,14 @@ func (conf *Config) WriteUserConfig() error {
 }
 
 // WriteLocalConfig writes the configuration to the project directory
+// Registry tokens cannot be stored in the project configuration.
 func (conf *Config) WriteProjectConfig(path string) error {
 
+	if conf.hasTokens() {
+		return errdefs.InvalidArgument(
+			"registry tokens can only be stored in the user configuration")
+	}
+
 	path = path + "/" + ProjectConfigFile
 	file, err := os.OpenFile(path, os.O_TRUNC|os.O_RDWR|os.O_CREATE, ConfigFilePerms)
 	if err != nil {
@@ -6,8 +6,14 @@ const (
 	ProjectConfigFile = "cneconfig"
 	ConfigFilePerms   = 0644
 
+	// ConfigSecretFilePerms are the permissions of configuration files with registry tokens
+	ConfigSecretFilePerms = 0600
+
 	DefaultPackageVersion = "latest"
 
+	DefaultStateDirName = "cne"
+	DefaultFailureLines = 100
+
 	DefaultContextName = "default"
 
 	DefaultRuntimeName       = "containerd"
@@ -0,0 +1,312 @@
+package config
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/czankel/cne/errdefs"
+)
+
+// Docker client configuration with the credentials for the registries
+const (
+	dockerConfigDir     = ".docker"
+	dockerConfigFile    = "config.json"
+	dockerConfigPerms   = 0600
+	dockerHubServerURL  = "https://index.docker.io/v1/"
+	dockerHubDomain     = "docker.io"
+	credentialHelperPfx = "docker-credential-"
+
+	// credentialsNotFound is the response of credential helpers for hosts without credentials
+	credentialsNotFound = "credentials not found"
+)
+
+// dockerAuth is the credential entry of a registry in the docker client configuration
+type dockerAuth struct {
+	Auth string `json:"auth,omitempty"`
+}
+
+// credentialHelperEntry is the format of the credentials used by credential helpers
+type credentialHelperEntry struct {
+	ServerURL string
+	Username  string
+	Secret    string
+}
+
+// dockerConfigPath returns the path of the docker client configuration, which is either in
+// the directory defined by DOCKER_CONFIG or in the .docker directory of the user.
+func dockerConfigPath(usr *User) string {
+
+	if dir := os.Getenv("DOCKER_CONFIG"); dir != "" {
+		return filepath.Join(dir, dockerConfigFile)
+	}
+	return filepath.Join(usr.HomeDir, dockerConfigDir, dockerConfigFile)
+}
+
+// readDockerConfig reads the docker client configuration. Fields not used by CNE are kept so
+// the configuration can be written back without losing them.
+func readDockerConfig(path string) (map[string]json.RawMessage, error) {
+
+	cfg := make(map[string]json.RawMessage)
+	data, err := os.ReadFile(path)
+	if err != nil && os.IsNotExist(err) {
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, errdefs.SystemError(err, "failed to read '%s'", path)
+	}
+	err = json.Unmarshal(data, &cfg)
+	if err != nil {
+		return nil, errdefs.InvalidArgument("docker config file '%s' corrupt", path)
+	}
+	return cfg, nil
+}
+
+// writeDockerConfig writes the docker client configuration and makes the current user the
+// owner if CNE runs with a different effective user.
+func writeDockerConfig(path string, cfg map[string]json.RawMessage) error {
+
+	data, err := json.MarshalIndent(cfg, "", "\t")
+	if err != nil {
+		return errdefs.SystemError(err, "failed to encode '%s'", path)
+	}
+
+	err = os.MkdirAll(filepath.Dir(path), 0700)
+	if err != nil {
+		return errdefs.SystemError(err, "failed to create directory for '%s'", path)
+	}
+	err = os.WriteFile(path, data, dockerConfigPerms)
+	if err != nil {
+		return errdefs.SystemError(err, "failed to write '%s'", path)
+	}
+
+	if os.Geteuid() != os.Getuid() {
+		err = os.Chown(path, os.Getuid(), os.Getgid())
+		if err != nil {
+			return errdefs.SystemError(err, "failed to update permissions for '%s'", path)
+		}
+	}
+	return nil
+}
+
+// dockerServerURL returns the key of the registry host in the docker client configuration.
+func dockerServerURL(host string) string {
+
+	switch host {
+	case dockerHubDomain, "registry-1.docker.io", "index.docker.io":
+		return dockerHubServerURL
+	}
+	return host
+}
+
+// dockerConfigField decodes the field of the docker client configuration if it exists.
+func dockerConfigField(cfg map[string]json.RawMessage, name string, v interface{}) error {
+
+	if raw, ok := cfg[name]; ok {
+		if err := json.Unmarshal(raw, v); err != nil {
+			return errdefs.InvalidArgument("docker config field '%s' corrupt", name)
+		}
+	}
+	return nil
+}
+
+// mirrorHost returns the host of a mirror endpoint in the format [scheme://]host[/path].
+func mirrorHost(mirror string) string {
+
+	if _, rest, ok := strings.Cut(mirror, "://"); ok {
+		mirror = rest
+	}
+	host, _, _ := strings.Cut(mirror, "/")
+	return host
+}
+
+// credentialHelper returns the credential helper configured for the host, if any.
+func credentialHelper(cfg map[string]json.RawMessage, host string) (string, error) {
+
+	var helpers map[string]string
+	err := dockerConfigField(cfg, "credHelpers", &helpers)
+	if err != nil {
+		return "", err
+	}
+	if helper, ok := helpers[dockerServerURL(host)]; ok {
+		return helper, nil
+	}
+	if helper, ok := helpers[host]; ok {
+		return helper, nil
+	}
+
+	var store string
+	err = dockerConfigField(cfg, "credsStore", &store)
+	return store, err
+}
+
+// runCredentialHelper runs the credential helper with the action and input and returns the
+// output of the helper. It returns ErrNotFound if the helper has no credentials for the host.
+func runCredentialHelper(helper, action, host string, input []byte) ([]byte, error) {
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(credentialHelperPfx+helper, action)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	if err != nil {
+		msg := strings.TrimSpace(stdout.String() + stderr.String())
+		if strings.Contains(msg, credentialsNotFound) {
+			return nil, errdefs.NotFound("credentials", host)
+		}
+		return nil, errdefs.SystemError(err, "credential helper '%s' failed: %s", helper, msg)
+	}
+	return stdout.Bytes(), nil
+}
+
+// Credentials returns the username and secret for the registry host. The credentials are looked
+// up in the configured registries and their mirrors first, followed by the credential helpers
+// and the stored credentials of the docker client configuration. It returns empty strings for
+// anonymous access.
+func (conf *Config) Credentials(usr *User, host string) (string, string, error) {
+
+	for _, reg := range conf.Registry {
+		if reg == nil || reg.Username == "" {
+			continue
+		}
+		if dockerServerURL(reg.Domain) == dockerServerURL(host) {
+			return reg.Username, reg.Token, nil
+		}
+		for _, mirror := range reg.Mirrors {
+			if mirrorHost(mirror) == host {
+				return reg.Username, reg.Token, nil
+			}
+		}
+	}
+
+	cfg, err := readDockerConfig(dockerConfigPath(usr))
+	if err != nil {
+		return "", "", err
+	}
+
+	helper, err := credentialHelper(cfg, host)
+	if err != nil {
+		return "", "", err
+	}
+	if helper != "" {
+		out, err := runCredentialHelper(helper, "get", host, []byte(dockerServerURL(host)))
+		if err != nil && errors.Is(err, errdefs.ErrNotFound) {
+			return "", "", nil
+		}
+		if err != nil {
+			return "", "", err
+		}
+		var entry credentialHelperEntry
+		if err := json.Unmarshal(out, &entry); err != nil {
+			return "", "", errdefs.InvalidArgument(
+				"invalid output from credential helper '%s'", helper)
+		}
+		return entry.Username, entry.Secret, nil
+	}
+
+	var auths map[string]dockerAuth
+	err = dockerConfigField(cfg, "auths", &auths)
+	if err != nil {
+		return "", "", err
+	}
+	for _, key := range []string{dockerServerURL(host), host, "https://" + host} {
+		auth, ok := auths[key]
+		if !ok || auth.Auth == "" {
+			continue
+		}
+		dec, err := base64.StdEncoding.DecodeString(auth.Auth)
+		if err != nil {
+			return "", "", errdefs.InvalidArgument("invalid credentials for '%s'", host)
+		}
+		username, secret, _ := strings.Cut(string(dec), ":")
+		return username, secret, nil
+	}
+	return "", "", nil
+}
+
+// Login stores the credentials for the registry host with the credential helper or in the
+// docker client configuration.
+func Login(usr *User, host, username, secret string) error {
+
+	path := dockerConfigPath(usr)
+	cfg, err := readDockerConfig(path)
+	if err != nil {
+		return err
+	}
+
+	helper, err := credentialHelper(cfg, host)
+	if err != nil {
+		return err
+	}
+	if helper != "" {
+		input, err := json.Marshal(credentialHelperEntry{
+			ServerURL: dockerServerURL(host),
+			Username:  username,
+			Secret:    secret,
+		})
+		if err != nil {
+			return errdefs.InternalError("failed to encode credentials: %v", err)
+		}
+		_, err = runCredentialHelper(helper, "store", host, input)
+		return err
+	}
+
+	auths := make(map[string]dockerAuth)
+	err = dockerConfigField(cfg, "auths", &auths)
+	if err != nil {
+		return err
+	}
+	if auths == nil {
+		auths = make(map[string]dockerAuth)
+	}
+	auths[dockerServerURL(host)] = dockerAuth{
+		Auth: base64.StdEncoding.EncodeToString([]byte(username + ":" + secret)),
+	}
+	cfg["auths"], err = json.Marshal(auths)
+	if err != nil {
+		return errdefs.InternalError("failed to encode credentials: %v", err)
+	}
+	return writeDockerConfig(path, cfg)
+}
+
+// Logout removes the stored credentials for the registry host. It returns ErrNotFound if no
+// credentials were stored for the host.
+func Logout(usr *User, host string) error {
+
+	path := dockerConfigPath(usr)
+	cfg, err := readDockerConfig(path)
+	if err != nil {
+		return err
+	}
+
+	helper, err := credentialHelper(cfg, host)
+	if err != nil {
+		return err
+	}
+	if helper != "" {
+		_, err = runCredentialHelper(helper, "erase", host, []byte(dockerServerURL(host)))
+		return err
+	}
+
+	var auths map[string]dockerAuth
+	err = dockerConfigField(cfg, "auths", &auths)
+	if err != nil {
+		return err
+	}
+	key := dockerServerURL(host)
+	if _, ok := auths[key]; !ok {
+		return errdefs.NotFound("credentials", host)
+	}
+	delete(auths, key)
+	cfg["auths"], err = json.Marshal(auths)
+	if err != nil {
+		return errdefs.InternalError("failed to encode credentials: %v", err)
+	}
+	return writeDockerConfig(path, cfg)
+}
@@ -0,0 +1,221 @@
+package config
+
+import (
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/czankel/cne/errdefs"
+)
+
+// testHelperScript is a credential helper that returns credentials for helper.example.com,
+// reports missing credentials for missing.example.com, and fails for all other hosts.
+const testHelperScript = `#!/bin/sh
+read host
+case "$1:$host" in
+get:helper.example.com)
+	echo '{"ServerURL":"helper.example.com","Username":"helper","Secret":"helper-secret"}';;
+get:missing.example.com)
+	echo "credentials not found in native keychain"; exit 1;;
+*)
+	echo "helper error" >&2; exit 1;;
+esac
+`
+
+// setupDockerConfig points DOCKER_CONFIG at a temporary directory with the provided docker
+// client configuration and adds the test credential helper to the PATH.
+func setupDockerConfig(t *testing.T, content string) func() {
+
+	dir := t.TempDir()
+	if content != "" {
+		err := os.WriteFile(filepath.Join(dir, dockerConfigFile), []byte(content), 0600)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err := os.WriteFile(filepath.Join(dir, credentialHelperPfx+"test"),
+		[]byte(testHelperScript), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	oldConfig, oldPath := os.Getenv("DOCKER_CONFIG"), os.Getenv("PATH")
+	os.Setenv("DOCKER_CONFIG", dir)
+	os.Setenv("PATH", dir+string(os.PathListSeparator)+oldPath)
+	return func() {
+		os.Setenv("DOCKER_CONFIG", oldConfig)
+		os.Setenv("PATH", oldPath)
+	}
+}
+
+func testAuth(username, secret string) string {
+	return base64.StdEncoding.EncodeToString([]byte(username + ":" + secret))
+}
+
+func TestCredentials(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		config   string
+		registry *Registry
+		host     string
+		username string
+		secret   string
+		err      error
+	}{
+		{
+			name:   "no config",
+			host:   "example.com",
+			config: "",
+		},
+		{
+			name:     "auths",
+			host:     "example.com",
+			config:   `{"auths":{"example.com":{"auth":"` + testAuth("user", "pass") + `"}}}`,
+			username: "user",
+			secret:   "pass",
+		},
+		{
+			name:     "auths with secret including colon",
+			host:     "example.com",
+			config:   `{"auths":{"https://example.com":{"auth":"` + testAuth("user", "a:b") + `"}}}`,
+			username: "user",
+			secret:   "a:b",
+		},
+		{
+			name:   "auths invalid encoding",
+			host:   "example.com",
+			config: `{"auths":{"example.com":{"auth":"%%%"}}}`,
+			err:    errdefs.ErrInvalidArgument,
+		},
+		{
+			name: "docker.io server URL",
+			host: "docker.io",
+			config: `{"auths":{"https://index.docker.io/v1/":{"auth":"` +
+				testAuth("hub", "hub-secret") + `"}}}`,
+			username: "hub",
+			secret:   "hub-secret",
+		},
+		{
+			name: "registry-1.docker.io server URL",
+			host: "registry-1.docker.io",
+			config: `{"auths":{"https://index.docker.io/v1/":{"auth":"` +
+				testAuth("hub", "hub-secret") + `"}}}`,
+			username: "hub",
+			secret:   "hub-secret",
+		},
+		{
+			name: "credHelpers before credsStore",
+			host: "helper.example.com",
+			config: `{"credsStore":"missing",` +
+				`"credHelpers":{"helper.example.com":"test"}}`,
+			username: "helper",
+			secret:   "helper-secret",
+		},
+		{
+			name: "credsStore before auths",
+			host: "helper.example.com",
+			config: `{"credsStore":"test",` +
+				`"auths":{"helper.example.com":{"auth":"` + testAuth("user", "pass") + `"}}}`,
+			username: "helper",
+			secret:   "helper-secret",
+		},
+		{
+			name:   "credential helper without credentials",
+			host:   "missing.example.com",
+			config: `{"credsStore":"test"}`,
+		},
+		{
+			name:   "credential helper error",
+			host:   "error.example.com",
+			config: `{"credsStore":"test"}`,
+			err:    errdefs.ErrSystemError,
+		},
+		{
+			name:     "registry configuration",
+			host:     "example.com",
+			config:   `{"auths":{"example.com":{"auth":"` + testAuth("user", "pass") + `"}}}`,
+			registry: &Registry{Domain: "example.com", Username: "reg", Token: "token"},
+			username: "reg",
+			secret:   "token",
+		},
+		{
+			name: "registry mirror",
+			host: "mirror.example.com:5000",
+			registry: &Registry{Domain: "example.com", Username: "reg", Token: "token",
+				Mirrors: []string{"https://mirror.example.com:5000/v2/example"}},
+			username: "reg",
+			secret:   "token",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer setupDockerConfig(t, tt.config)()
+
+			conf := &Config{Registry: map[string]*Registry{}}
+			if tt.registry != nil {
+				conf.Registry["test"] = tt.registry
+			}
+			username, secret, err := conf.Credentials(&User{}, tt.host)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Errorf("Expected error '%v', got '%v'", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if username != tt.username || secret != tt.secret {
+				t.Errorf("Expected '%s:%s', got '%s:%s'", tt.username, tt.secret, username, secret)
+			}
+		})
+	}
+}
+
+func TestLoginLogout(t *testing.T) {
+
+	defer setupDockerConfig(t, `{"other":"value"}`)()
+
+	usr := &User{}
+	err := Logout(usr, "docker.io")
+	if !errors.Is(err, errdefs.ErrNotFound) {
+		t.Errorf("Logout without credentials should fail with not found: %v", err)
+	}
+
+	err = Login(usr, "docker.io", "user", "pass")
+	if err != nil {
+		t.Fatalf("Login failed: %v", err)
+	}
+
+	cfg, err := readDockerConfig(dockerConfigPath(usr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := cfg["other"]; !ok {
+		t.Errorf("Login should have kept other fields of the configuration")
+	}
+	var auths map[string]dockerAuth
+	dockerConfigField(cfg, "auths", &auths)
+	if auths[dockerHubServerURL].Auth != testAuth("user", "pass") {
+		t.Errorf("Login should have stored the credentials for the server URL: %v", auths)
+	}
+
+	conf := &Config{}
+	username, secret, err := conf.Credentials(usr, "index.docker.io")
+	if err != nil || username != "user" || secret != "pass" {
+		t.Errorf("Unexpected credentials '%s:%s': %v", username, secret, err)
+	}
+
+	err = Logout(usr, "docker.io")
+	if err != nil {
+		t.Fatalf("Logout failed: %v", err)
+	}
+	err = Logout(usr, "docker.io")
+	if !errors.Is(err, errdefs.ErrNotFound) {
+		t.Errorf("Second logout should fail with not found: %v", err)
+	}
+}
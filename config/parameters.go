@@ -8,4 +8,5 @@ const (
 
 type Parameters struct {
 	Upgrade string // upgrade the listed components during container rebuilt
+	NoCache bool   // don't use the build cache for the layers that are rebuilt
 }
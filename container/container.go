@@ -4,12 +4,18 @@ package container
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"strconv"
 	"strings"
+	"syscall"
 	"time"
 
+	"github.com/containerd/console"
 	"github.com/google/uuid"
 	"github.com/opencontainers/image-spec/identity"
 
@@ -91,9 +97,18 @@ func CreateContainer(ctx context.Context, run runtime.Runtime, ws *project.Works
 		return nil, errdefs.InvalidArgument("invalid project UUID: '%v'", ws.ProjectUUID)
 	}
 
+	labels := map[string]string{
+		runtime.WorkspaceNameLabel: ws.Name,
+		runtime.VersionLabel:       config.CneVersion,
+	}
+	if prj := ws.Project(); prj != nil {
+		labels[runtime.ProjectNameLabel] = prj.Name
+		labels[runtime.ProjectPathLabel] = filepath.Dir(prj.Path)
+	}
+
 	cid := ws.ID()
 	gen := ws.BaseHash()
-	runCtr, err := run.NewContainer(ctx, dom, cid, gen, user.UID)
+	runCtr, err := run.NewContainer(ctx, dom, cid, gen, user.UID, labels)
 	if err != nil {
 		return nil, err
 	}
@@ -101,37 +116,324 @@ func CreateContainer(ctx context.Context, run runtime.Runtime, ws *project.Works
 	return runCtr, runCtr.Create(ctx, img, options)
 }
 
-// find RootFS looks up the top-most snapshot up to but excluding nextLayerIdx
-// and returns the digest and layer index. ErrNotFound is returned if no snapshot was found.
-func findRootFS(ctx context.Context, runCtr runtime.Container,
-	ws *project.Workspace, nextLayerIdx int) (int, string, error) {
+// rootSnapshot returns the name of the snapshot the first layer of the workspace is built on.
+// This is the top-most layer snapshot of the base workspace, or the image snapshot if the
+// workspace doesn't have a base.
+func rootSnapshot(ctx context.Context, img runtime.Image, ws *project.Workspace) (string, error) {
+
+	base, err := ws.BaseWorkspace()
+	if err != nil {
+		return "", err
+	}
+
+	if base == nil {
+		diffIDs, err := img.RootFS(ctx)
+		if err != nil {
+			return "", runtime.Errorf("failed to get rootfs: %v", err)
+		}
+		return identity.ChainID(diffIDs).String(), nil
+	}
+
+	layers := base.Environment.Layers
+	for i := len(layers) - 1; i >= 0; i-- {
+		if layers[i].Digest != "" {
+			return layers[i].Digest, nil
+		}
+	}
+	return rootSnapshot(ctx, img, base)
+}
+
+// hasAncestor checks if the snapshot is derived from the provided root snapshot.
+func hasAncestor(parents map[string]string, snapName, rootName string) bool {
+
+	for i := 0; i <= len(parents) && snapName != ""; i++ {
+		if snapName == rootName {
+			return true
+		}
+		snapName = parents[snapName]
+	}
+	return false
+}
+
+// find RootFS looks up the top-most snapshot up to but excluding nextLayerIdx that is
+// derived from the provided root snapshot and returns the digest and layer index.
+// ErrNotFound is returned if no snapshot was found.
+func findRootFS(snaps []runtime.Snapshot,
+	ws *project.Workspace, nextLayerIdx int, rootName string) (int, string, error) {
 
 	// identify the layer with the topmost existing snapshot
 	layerIdx := 0
-	snaps, err := runCtr.Snapshots(ctx)
-	if err != nil {
-		return -1, "", err
+	parents := make(map[string]string, len(snaps))
+	for _, s := range snaps {
+		parents[s.Name()] = s.Parent()
 	}
 
-	var snapName string
+	// stop at the first layer that needs to be rebuilt, as the snapshots of opaque layers
+	// following the layer are kept
+	snapName := rootName
 	for i := 0; i < nextLayerIdx; i++ {
-		if l := ws.Environment.Layers[i]; l.Digest != "" {
-			for _, s := range snaps {
-				if l.Digest == s.Name() {
-					layerIdx = i + 1
-					snapName = l.Digest
-					break
-				}
-			}
+		l := ws.Environment.Layers[i]
+		if l.Disabled {
+			continue
+		}
+		if _, ok := parents[l.Digest]; !ok || !hasAncestor(parents, l.Digest, snapName) {
+			break
 		}
+		layerIdx = i + 1
+		snapName = l.Digest
 	}
 	if layerIdx == 0 {
-		return 0, "", errdefs.NotFound("snapshot", snapName)
+		return 0, "", errdefs.NotFound("snapshot", rootName)
 	}
 
 	return layerIdx, snapName, nil
 }
 
+// committedOn checks if the snapshot exists and was created on top of the parent snapshot.
+func committedOn(snaps []runtime.Snapshot, snapName, parent string) bool {
+
+	parents := make(map[string]string, len(snaps))
+	for _, s := range snaps {
+		parents[s.Name()] = s.Parent()
+	}
+	_, ok := parents[snapName]
+	return ok && hasAncestor(parents, snapName, parent)
+}
+
+// opaqueLayerConflict returns the error for an opaque layer that was committed on top of
+// different layers than the layers it would be built on.
+func opaqueLayerConflict(layer *project.Layer) error {
+	return errdefs.InvalidArgument(
+		"snapshot layer '%s' was committed on top of different layers and cannot be "+
+			"rebuilt; delete the layer and commit it again", layer.Name)
+}
+
+// cacheKey returns the build cache key of a layer built on top of the parent snapshot by the
+// provided user and the expanded commands and their environment variables.
+func cacheKey(parent string, user *config.User, args [][]string, envs [][]string) string {
+
+	h := sha256.New()
+	h.Write([]byte(parent))
+	h.Write([]byte{0})
+	h.Write([]byte(strconv.FormatUint(uint64(user.BuildUID), 10) + ":" +
+		strconv.FormatUint(uint64(user.BuildGID), 10)))
+	h.Write([]byte{0})
+	for i := range args {
+		for _, a := range args[i] {
+			h.Write([]byte(a))
+			h.Write([]byte{0})
+		}
+		h.Write([]byte{1})
+		for _, e := range envs[i] {
+			h.Write([]byte(e))
+			h.Write([]byte{0})
+		}
+		h.Write([]byte{2})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// findCachedSnapshot returns the name of the snapshot with the cache key and parent snapshot
+// or an empty string if there is no such snapshot.
+func findCachedSnapshot(snaps []runtime.Snapshot, parent, key string) string {
+
+	for _, s := range snaps {
+		if s.Parent() == parent && s.Labels()[runtime.CacheKeyLabel] == key {
+			return s.Name()
+		}
+	}
+	return ""
+}
+
+// expandCommands expands the templates of all enabled commands of the layer and returns the
+// arguments and environment variables of the commands that are not empty.
+func expandCommands(layer *project.Layer, vars templateVars) ([][]string, [][]string, error) {
+
+	var args [][]string
+	var envs [][]string
+	for _, command := range layer.Commands {
+		if command.Disabled {
+			continue
+		}
+		a, err := expandLine(command.Args, vars)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(a) > 0 {
+			args = append(args, a)
+			envs = append(envs, command.Envs)
+		}
+	}
+	return args, envs, nil
+}
+
+// templateVars are the variables that can be used in templates of commands.
+type templateVars struct {
+	Environment *project.Environment
+	User        *config.User
+	Parameters  *config.Parameters
+}
+
+// ValidateCommands checks the syntax of the commands and the templates used in the commands
+// without executing them.
+func ValidateCommands(ws *project.Workspace, user *config.User, params *config.Parameters,
+	commands []project.Command) error {
+
+	vars := templateVars{
+		Environment: &ws.Environment,
+		User:        user,
+		Parameters:  params,
+	}
+
+	for i, c := range commands {
+		if len(c.Args) == 0 {
+			return errdefs.InvalidArgument("command %d has no arguments", i)
+		}
+		for _, e := range c.Envs {
+			if !strings.Contains(e, "=") {
+				return errdefs.InvalidArgument(
+					"invalid environment variable in command %d: '%s'", i, e)
+			}
+		}
+		if _, err := expandLine(c.Args, vars); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LayerPlan describes if a layer is cached or would be built and the expanded commands.
+type LayerPlan struct {
+	Name     string
+	Status   string // runtime.StatusCached, StatusPending, StatusSkipped, or StatusError
+	Commands [][]string
+}
+
+// Plan returns the name of the snapshot the build would start from and describes for each
+// layer up to layerCount if the layer is cached or would be built, without building anything.
+// The image can be nil if it hasn't been pulled, in which case all layers would be built.
+func Plan(ctx context.Context, run runtime.Runtime, img runtime.Image,
+	ws *project.Workspace, layerCount int,
+	user *config.User, params *config.Parameters) (string, []LayerPlan, error) {
+
+	if layerCount == -1 {
+		layerCount = len(ws.Environment.Layers)
+	}
+
+	layerIdx := 0
+	name := ""
+	var snaps []runtime.Snapshot
+	if img != nil {
+		rootName, err := rootSnapshot(ctx, img, ws)
+		if err != nil {
+			return "", nil, err
+		}
+		snaps, err = run.Snapshots(ctx)
+		if err != nil {
+			return "", nil, err
+		}
+		layerIdx, name, err = findRootFS(snaps, ws, layerCount, rootName)
+		if err != nil && errors.Is(err, errdefs.ErrNotFound) {
+			layerIdx = 0
+			name = rootName
+		} else if err != nil {
+			return "", nil, err
+		}
+	}
+
+	vars := templateVars{
+		Environment: &ws.Environment,
+		User:        user,
+		Parameters:  params,
+	}
+
+	// name tracks the parent snapshot of the next layer for build cache lookups
+	rootFS := name
+	plan := make([]LayerPlan, layerCount)
+	for i := 0; i < layerCount; i++ {
+		layer := &ws.Environment.Layers[i]
+		plan[i].Name = layer.Name
+		if layer.Disabled {
+			plan[i].Status = runtime.StatusSkipped
+			continue
+		}
+
+		args, envs, err := expandCommands(layer, vars)
+		if err != nil {
+			return "", nil, err
+		}
+		plan[i].Commands = args
+
+		switch {
+		case i < layerIdx:
+			plan[i].Status = runtime.StatusCached
+		case layer.Opaque && name != "" && committedOn(snaps, layer.Digest, name):
+			plan[i].Status = runtime.StatusCached
+			name = layer.Digest
+		case layer.Opaque:
+			plan[i].Status = runtime.StatusError
+			name = ""
+		case name != "" && !params.NoCache:
+			name = findCachedSnapshot(snaps, name, cacheKey(name, user, args, envs))
+			plan[i].Status = runtime.StatusPending
+			if name != "" {
+				plan[i].Status = runtime.StatusCached
+			}
+		default:
+			plan[i].Status = runtime.StatusPending
+			name = ""
+		}
+	}
+
+	return rootFS, plan, nil
+}
+
+// CacheSnapshots returns the names of the layer snapshots of the workspace, including the
+// layer snapshots of any base workspace, from the image to the topmost built layer. Parent
+// snapshots come before their children. It returns ErrNotFound if no layer has been built.
+func CacheSnapshots(ctx context.Context, run runtime.Runtime, img runtime.Image,
+	ws *project.Workspace) ([]string, error) {
+
+	rootName, err := rootSnapshot(ctx, img, ws)
+	if err != nil {
+		return nil, err
+	}
+
+	snaps, err := run.Snapshots(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	_, name, err := findRootFS(snaps, ws, len(ws.Environment.Layers), rootName)
+	if err != nil {
+		return nil, err
+	}
+
+	diffIDs, err := img.RootFS(ctx)
+	if err != nil {
+		return nil, runtime.Errorf("failed to get rootfs: %v", err)
+	}
+	imgName := identity.ChainID(diffIDs).String()
+
+	parents := make(map[string]string, len(snaps))
+	for _, s := range snaps {
+		parents[s.Name()] = s.Parent()
+	}
+
+	var names []string
+	for ; name != imgName && name != ""; name = parents[name] {
+		names = append([]string{name}, names...)
+	}
+	if name != imgName {
+		return nil, errdefs.NotFound("snapshot", imgName)
+	}
+
+	return names, nil
+}
+
+// LayerStreams returns the stream for the output of the commands of the layer.
+type LayerStreams func(layerIdx int, layer *project.Layer) runtime.Stream
+
 // Build builds the container.
 //
 // A container may already be partially built. In that case, Build() will continue the build
@@ -139,23 +441,34 @@ func findRootFS(ctx context.Context, runCtr runtime.Container,
 //
 // layerCount determines the number of layers built. Use 0 to only create the image and
 // -1 or len(layers) to build all layers.
+// Workspaces with a base workspace are built on top of the base workspace, which must have
+// been built before. Disabled layers and commands are skipped. Opaque layers cannot be rebuilt
+// and return an error if they weren't committed on top of the layers below.
 // The progress argument is optional for outputting status updates during the build process.
+// The streams function returns the stream for the output of the commands of a layer and is
+// called before the commands of the layer are executed.
 func Build(ctx context.Context, run runtime.Runtime, runCtr runtime.Container,
 	img runtime.Image, ws *project.Workspace, layerCount int,
 	user *config.User, params *config.Parameters,
-	progress chan []runtime.ProgressStatus, stream runtime.Stream) error {
+	progress chan []runtime.ProgressStatus, streams LayerStreams) error {
 
 	if layerCount == -1 {
 		layerCount = len(ws.Environment.Layers)
 	}
 
-	layerIdx, name, err := findRootFS(ctx, runCtr, ws, layerCount)
+	rootName, err := rootSnapshot(ctx, img, ws)
+	if err != nil {
+		return err
+	}
+
+	snaps, err := runCtr.Snapshots(ctx)
+	if err != nil {
+		return err
+	}
+
+	layerIdx, name, err := findRootFS(snaps, ws, layerCount, rootName)
 	if err != nil && errors.Is(err, errdefs.ErrNotFound) {
-		diffIDs, err := img.RootFS(ctx)
-		if err != nil {
-			return runtime.Errorf("failed to get rootfs: %v", err)
-		}
-		name = identity.ChainID(diffIDs).String()
+		name = rootName
 		_, err = run.GetSnapshot(ctx, name)
 		if err != nil {
 			return err
@@ -171,7 +484,9 @@ func Build(ctx context.Context, run runtime.Runtime, runCtr runtime.Container,
 			layerStatus[i].StartedAt = time.Now()
 			layerStatus[i].UpdatedAt = time.Now()
 
-			if i < layerIdx {
+			if l.Disabled {
+				layerStatus[i].Status = runtime.StatusSkipped
+			} else if i < layerIdx {
 				layerStatus[i].Status = runtime.StatusCached
 				layerStatus[i].Offset = layerStatus[i].Total
 			} else {
@@ -179,7 +494,7 @@ func Build(ctx context.Context, run runtime.Runtime, runCtr runtime.Container,
 				layerStatus[i].Total = int64(len(l.Commands))
 			}
 		}
-		var stat []runtime.ProgressStatus
+		stat := make([]runtime.ProgressStatus, len(layerStatus))
 		copy(stat, layerStatus)
 		progress <- stat
 	}
@@ -189,11 +504,14 @@ func Build(ctx context.Context, run runtime.Runtime, runCtr runtime.Container,
 		return err
 	}
 
-	vars := struct {
-		Environment *project.Environment
-		User        *config.User
-		Parameters  *config.Parameters
-	}{
+	// setting the root filesystem deletes snapshots that are no longer used
+	snaps, err = runCtr.Snapshots(ctx)
+	if err != nil {
+		runCtr.Delete(ctx) // ignore error
+		return err
+	}
+
+	vars := templateVars{
 		Environment: &ws.Environment,
 		User:        user,
 		Parameters:  params,
@@ -203,17 +521,65 @@ func Build(ctx context.Context, run runtime.Runtime, runCtr runtime.Container,
 	for ; layerIdx < layerCount; layerIdx++ {
 
 		layer := &ws.Environment.Layers[layerIdx]
-		for _, command := range layer.Commands {
 
-			args, err := expandLine(command.Args, vars)
+		// disabled layers don't have a snapshot
+		if layer.Disabled {
+			layer.Digest = ""
+			continue
+		}
+
+		// opaque layers can only be used on top of the layers they were committed on
+		if layer.Opaque {
+			if name == "" || !committedOn(snaps, layer.Digest, name) {
+				runCtr.Delete(ctx) // ignore error
+				return opaqueLayerConflict(layer)
+			}
+			err = runCtr.SetRootFS(ctx, layer.Digest)
 			if err != nil {
 				runCtr.Delete(ctx) // ignore error
 				return err
 			}
+			name = layer.Digest
+			if progress != nil {
+				layerStatus[layerIdx].Status = runtime.StatusCached
+				layerStatus[layerIdx].UpdatedAt = time.Now()
+				stat := []runtime.ProgressStatus{layerStatus[layerIdx]}
+				progress <- stat
+			}
+			continue
+		}
 
-			if len(args) == 0 {
-				continue
+		cmdArgs, cmdEnvs, err := expandCommands(layer, vars)
+		if err != nil {
+			runCtr.Delete(ctx) // ignore error
+			return err
+		}
+
+		// use the snapshot of an identical layer built on the same parent snapshot
+		key := cacheKey(name, user, cmdArgs, cmdEnvs)
+		cached := ""
+		if name != "" && !params.NoCache {
+			cached = findCachedSnapshot(snaps, name, key)
+		}
+		if cached != "" {
+			err = runCtr.SetRootFS(ctx, cached)
+			if err != nil {
+				runCtr.Delete(ctx) // ignore error
+				return err
+			}
+			layer.Digest = cached
+			name = cached
+			if progress != nil {
+				layerStatus[layerIdx].Status = runtime.StatusCached
+				layerStatus[layerIdx].UpdatedAt = time.Now()
+				stat := []runtime.ProgressStatus{layerStatus[layerIdx]}
+				progress <- stat
 			}
+			continue
+		}
+
+		stream := streams(layerIdx, layer)
+		for i, args := range cmdArgs {
 
 			if progress != nil {
 				lineOut := "Executing: " + strings.Join(args, " ")
@@ -222,10 +588,11 @@ func Build(ctx context.Context, run runtime.Runtime, runCtr runtime.Container,
 				}
 				layerStatus[layerIdx].Status = runtime.StatusRunning
 				layerStatus[layerIdx].Details = lineOut
+				layerStatus[layerIdx].UpdatedAt = time.Now()
 				stat := []runtime.ProgressStatus{layerStatus[layerIdx]}
 				progress <- stat
 			}
-			code, err := BuildExec(ctx, runCtr, user, stream, args, command.Envs)
+			code, err := BuildExec(ctx, runCtr, user, stream, args, cmdEnvs[i])
 			if code != 0 {
 				err = errdefs.CommandFailed(args)
 			}
@@ -235,7 +602,7 @@ func Build(ctx context.Context, run runtime.Runtime, runCtr runtime.Container,
 			}
 		}
 
-		// create a snapshot for the layer
+		// create a snapshot for the layer and record the cache key
 		layer.Digest = ""
 		snap, err := runCtr.Snapshot(ctx)
 		if err != nil &&
@@ -244,11 +611,23 @@ func Build(ctx context.Context, run runtime.Runtime, runCtr runtime.Container,
 			runCtr.Delete(ctx)
 			return err
 		}
+		name = ""
 		if snap != nil {
 			layer.Digest = snap.Name()
+			name = snap.Name()
+			err = run.SetSnapshotLabel(ctx, name, runtime.CacheKeyLabel, key)
+			if err == nil {
+				err = run.SetSnapshotLabel(ctx, name,
+					runtime.WorkspaceLabel, ws.ProjectUUID+"/"+ws.Name)
+			}
+			if err != nil && !errors.Is(err, errdefs.ErrNotImplemented) {
+				runCtr.Delete(ctx)
+				return err
+			}
 		}
 		if progress != nil {
 			layerStatus[layerIdx].Status = runtime.StatusComplete
+			layerStatus[layerIdx].UpdatedAt = time.Now()
 			stat := []runtime.ProgressStatus{layerStatus[layerIdx]}
 			progress <- stat
 		}
@@ -257,6 +636,48 @@ func Build(ctx context.Context, run runtime.Runtime, runCtr runtime.Container,
 	return nil
 }
 
+// CommitLayer creates a snapshot of the changes made in the container and adds the snapshot
+// as a new opaque top layer to the workspace. The container must be the active container of
+// the workspace and any running processes in the container are stopped. The snapshot is
+// labeled with the workspace, so it is kept when the layers below are rebuilt.
+func CommitLayer(ctx context.Context, run runtime.Runtime, runCtr runtime.Container,
+	ws *project.Workspace, name string) (*project.Layer, error) {
+
+	if err := project.CheckLayerNames([]project.Layer{{Name: name}}); err != nil {
+		return nil, err
+	}
+	if _, _, err := ws.FindLayer(name); err == nil {
+		return nil, errdefs.AlreadyExists("layer", name)
+	}
+
+	snap, err := runCtr.Snapshot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if snap == nil {
+		return nil, errdefs.InvalidArgument("no changes to commit")
+	}
+	err = run.SetSnapshotLabel(ctx, snap.Name(),
+		runtime.WorkspaceLabel, ws.ProjectUUID+"/"+ws.Name)
+	if err != nil && !errors.Is(err, errdefs.ErrNotImplemented) {
+		return nil, err
+	}
+
+	_, layer, err := ws.CreateLayer(name, "")
+	if err != nil {
+		return nil, err
+	}
+	layer.Opaque = true
+	layer.Digest = snap.Name()
+
+	err = runCtr.Commit(ctx, ws.ConfigHash())
+	if err != nil {
+		return nil, err
+	}
+
+	return layer, nil
+}
+
 // Exec excutes the provided command, using the default proces runtime spec.
 // The user defines the current working directory and UID and GID.
 // It uses the default environment from the calling process.
@@ -285,6 +706,27 @@ func Exec(ctx context.Context, runCtr runtime.Container,
 	return commonExec(ctx, runCtr, &procSpec, stream)
 }
 
+// Shell starts the shell of the user as a login shell in the current working directory.
+// The provided environment variables are added to the environment of the calling process.
+// The container must be started before calling this function.
+func Shell(ctx context.Context, runCtr runtime.Container,
+	user *config.User, stream runtime.Stream, envs []string) (uint32, error) {
+
+	procSpec := runtime.ProcessSpec{
+		Cwd:  user.Pwd,
+		UID:  user.UID,
+		GID:  user.GID,
+		Args: []string{user.Shell, "-l"},
+		Env:  append(os.Environ(), envs...),
+	}
+
+	if user.IsSudo {
+		procSpec.UID = 0
+	}
+
+	return commonExec(ctx, runCtr, &procSpec, stream)
+}
+
 func BuildExec(ctx context.Context, runCtr runtime.Container,
 	user *config.User, stream runtime.Stream,
 	args []string, envs []string) (uint32, error) {
@@ -311,6 +753,10 @@ func commonExec(ctx context.Context, runCtr runtime.Container,
 		return 0, err
 	}
 
+	if stream.Terminal {
+		resizeProcess(ctx, proc) // ignore error
+	}
+
 	sigc := make(chan os.Signal, 1)
 	signal.Notify(sigc)
 	go func() {
@@ -319,6 +765,12 @@ func commonExec(ctx context.Context, runCtr runtime.Container,
 			if !more {
 				return
 			}
+			if s == syscall.SIGWINCH {
+				if stream.Terminal {
+					resizeProcess(ctx, proc)
+				}
+				continue
+			}
 			proc.Signal(ctx, s)
 		}
 	}()
@@ -328,3 +780,19 @@ func commonExec(ctx context.Context, runCtr runtime.Container,
 	close(sigc)
 	return exitStat.Code, exitStat.Error
 }
+
+// resizeProcess resizes the terminal of the process to the size of the current console.
+func resizeProcess(ctx context.Context, proc runtime.Process) error {
+
+	con, err := console.ConsoleFromFile(os.Stdout)
+	if err != nil {
+		return err
+	}
+
+	winSz, err := con.Size()
+	if err != nil {
+		return err
+	}
+
+	return proc.Resize(ctx, uint32(winSz.Width), uint32(winSz.Height))
+}
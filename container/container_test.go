@@ -0,0 +1,199 @@
+package container
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	digest "github.com/opencontainers/go-digest"
+
+	"github.com/czankel/cne/config"
+	"github.com/czankel/cne/errdefs"
+	"github.com/czankel/cne/project"
+	"github.com/czankel/cne/runtime"
+)
+
+type testSnapshot struct {
+	name   string
+	parent string
+	labels map[string]string
+}
+
+func (s *testSnapshot) Name() string              { return s.name }
+func (s *testSnapshot) Parent() string            { return s.parent }
+func (s *testSnapshot) CreatedAt() time.Time      { return time.Time{} }
+func (s *testSnapshot) Size() int64               { return 0 }
+func (s *testSnapshot) Inodes() int64             { return 0 }
+func (s *testSnapshot) Labels() map[string]string { return s.labels }
+
+func TestFindRootFSOpaque(t *testing.T) {
+
+	snaps := []runtime.Snapshot{
+		&testSnapshot{name: "root"},
+		&testSnapshot{name: "l0", parent: "root"},
+		&testSnapshot{name: "snap", parent: "l0"},
+		&testSnapshot{name: "l0-new", parent: "root"},
+	}
+
+	ws := &project.Workspace{
+		Environment: project.Environment{
+			Layers: []project.Layer{
+				{Name: "l0", Digest: "l0"},
+				{Name: "snap", Digest: "snap", Opaque: true},
+			},
+		},
+	}
+
+	idx, name, err := findRootFS(snaps, ws, 2, "root")
+	if err != nil || idx != 2 || name != "snap" {
+		t.Errorf("Should have found the opaque layer snapshot: %d %s %v", idx, name, err)
+	}
+
+	// the opaque layer must not be used if a layer below has to be rebuilt
+	ws.Environment.Layers[0].Digest = ""
+	_, _, err = findRootFS(snaps, ws, 2, "root")
+	if !errors.Is(err, errdefs.ErrNotFound) {
+		t.Errorf("Should not have found a snapshot: %v", err)
+	}
+
+	if !committedOn(snaps, "snap", "l0") {
+		t.Errorf("Snapshot should have been committed on the layer")
+	}
+	if committedOn(snaps, "snap", "l0-new") {
+		t.Errorf("Snapshot should not have been committed on the rebuilt layer")
+	}
+}
+
+// testSnapshots are the snapshots of the test runtime with the active snapshot of the container.
+type testSnapshots struct {
+	snaps map[string]*testSnapshot
+	count int
+}
+
+const testActiveSnapshot = "active"
+
+type testRuntime struct {
+	runtime.Runtime
+	*testSnapshots
+}
+
+func (run *testRuntime) GetSnapshot(ctx context.Context, name string) (runtime.Snapshot, error) {
+	if s, ok := run.snaps[name]; ok {
+		return s, nil
+	}
+	return nil, errdefs.NotFound("snapshot", name)
+}
+
+func (run *testRuntime) SetSnapshotLabel(ctx context.Context, name, key, value string) error {
+	s, ok := run.snaps[name]
+	if !ok {
+		return errdefs.NotFound("snapshot", name)
+	}
+	if s.labels == nil {
+		s.labels = make(map[string]string)
+	}
+	s.labels[key] = value
+	return nil
+}
+
+type testImage struct {
+	runtime.Image
+	diffIDs []digest.Digest
+}
+
+func (img *testImage) RootFS(ctx context.Context) ([]digest.Digest, error) {
+	return img.diffIDs, nil
+}
+
+// testContainer deletes the unused snapshots when the root filesystem is set like the
+// containerd runtime.
+type testContainer struct {
+	runtime.Container
+	*testSnapshots
+}
+
+func (ctr *testContainer) Snapshots(ctx context.Context) ([]runtime.Snapshot, error) {
+	var snaps []runtime.Snapshot
+	for _, s := range ctr.snaps {
+		snaps = append(snaps, s)
+	}
+	return snaps, nil
+}
+
+func (ctr *testContainer) SetRootFS(ctx context.Context, snapName string) error {
+	for name := testActiveSnapshot; name != snapName; {
+		s, ok := ctr.snaps[name]
+		if !ok || runtime.IsLayerSnapshot(s) {
+			break
+		}
+		delete(ctr.snaps, name)
+		name = s.parent
+	}
+	if _, ok := ctr.snaps[snapName]; !ok {
+		return errdefs.NotFound("snapshot", snapName)
+	}
+	ctr.snaps[testActiveSnapshot] = &testSnapshot{name: testActiveSnapshot, parent: snapName}
+	return nil
+}
+
+func (ctr *testContainer) Snapshot(ctx context.Context) (runtime.Snapshot, error) {
+	ctr.count++
+	active := ctr.snaps[testActiveSnapshot]
+	snap := &testSnapshot{name: fmt.Sprintf("snap%d", ctr.count), parent: active.parent}
+	ctr.snaps[snap.name] = snap
+	active.parent = snap.name
+	return snap, nil
+}
+
+func (ctr *testContainer) Commit(ctx context.Context, generation [16]byte) error { return nil }
+func (ctr *testContainer) Delete(ctx context.Context) error                      { return nil }
+
+func TestBuildFromKeepsOpaqueLayer(t *testing.T) {
+
+	ctx := context.Background()
+	rootName := digest.FromString("root")
+	snaps := &testSnapshots{snaps: map[string]*testSnapshot{
+		rootName.String(): {name: rootName.String()},
+		"l0": {name: "l0", parent: rootName.String(),
+			labels: map[string]string{runtime.CacheKeyLabel: "key"}},
+	}}
+	run := &testRuntime{testSnapshots: snaps}
+	ctr := &testContainer{testSnapshots: snaps}
+	img := &testImage{diffIDs: []digest.Digest{rootName}}
+
+	ws := &project.Workspace{
+		Name: "ws",
+		Environment: project.Environment{
+			Layers: []project.Layer{{Name: "l0", Digest: "l0"}},
+		},
+	}
+
+	if err := ctr.SetRootFS(ctx, "l0"); err != nil {
+		t.Fatalf("Failed to set root filesystem: %v", err)
+	}
+	layer, err := CommitLayer(ctx, run, ctr, ws, "opaque")
+	if err != nil {
+		t.Fatalf("Failed to commit layer: %v", err)
+	}
+	if !runtime.IsLayerSnapshot(snaps.snaps[layer.Digest]) {
+		t.Errorf("Committed snapshot should have been labeled")
+	}
+	opaqueDigest := layer.Digest
+
+	// rebuild from the layer below the opaque layer
+	ws.UpdateLayer(&ws.Environment.Layers[0])
+	err = Build(ctx, run, ctr, img, ws, -1, &config.User{},
+		&config.Parameters{NoCache: true}, nil,
+		func(int, *project.Layer) runtime.Stream { return runtime.Stream{} })
+	if !errors.Is(err, errdefs.ErrInvalidArgument) {
+		t.Errorf("Build should have reported the conflict of the opaque layer: %v", err)
+	}
+	if _, ok := snaps.snaps[opaqueDigest]; !ok {
+		t.Errorf("Snapshot of the opaque layer should have been kept")
+	}
+	if ws.Environment.Layers[1].Digest != opaqueDigest {
+		t.Errorf("Opaque layer should have kept its snapshot")
+	}
+}
@@ -87,7 +87,7 @@ func expandLine(line []string, vars interface{}) ([]string, error) {
 
 	for _, arg := range line {
 
-		if arg[0] != '{' || arg[1] != '{' {
+		if len(arg) < 2 || arg[0] != '{' || arg[1] != '{' {
 			if skip == 0 {
 				cmds = append(cmds, arg)
 			}
@@ -40,6 +40,8 @@ func TestTemplateVars(t *testing.T) {
 	testcases := []testcase{
 		{"malformed", []string{"{{"}, []string{"E"}},
 		{"empty", []string{}, []string{}},
+		{"empty arg", []string{""}, []string{""}},
+		{"single brace", []string{"{"}, []string{"{"}},
 		{"normal text", []string{"normal", "text"}, []string{"normal", "text"}},
 		{"text with spaces", []string{"text", "with spaces"}, []string{"text", "with spaces"}},
 		{"templ only space", []string{"{{        }}"}, []string{}},
@@ -28,6 +28,9 @@ var (
 	ErrInternalError = errors.New("internal error")
 	// error: internal error: <description>
 	ErrInUse = errors.New("in use")
+	// error: <resource> '<name>' is in use
+	ErrConflict = errors.New("conflict")
+	// error: <resource> '<name>' was modified concurrently
 
 	// pass-through errors
 	ErrCommandFailed   = errors.New("cmd failed")
@@ -128,6 +131,14 @@ func InUse(resource, name string) error {
 	}
 }
 
+func Conflict(resource, name string) error {
+	return &cneError{
+		cause:    ErrConflict,
+		resource: resource,
+		msg:      fmt.Sprintf("%s '%s' was modified concurrently", resource, name),
+	}
+}
+
 func InternalError(format string, args ...interface{}) error {
 	return &cneError{
 		cause: ErrInternalError,
@@ -0,0 +1,70 @@
+package project
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"syscall"
+
+	"github.com/czankel/cne/errdefs"
+)
+
+// Lock is an advisory lock for serializing loading, modifying, and writing a project file
+// between multiple processes. The lock is held on the directory of the project file, as the
+// project file itself is replaced when it is written.
+type Lock struct {
+	file *os.File
+}
+
+// LockProject acquires an exclusive lock for the project file in the provided path.
+// It blocks until the lock becomes available. The optional wait function is called before
+// blocking if the lock is held by another process.
+func LockProject(path string, wait func()) (*Lock, error) {
+	return lockProject(path, syscall.LOCK_EX, wait)
+}
+
+// LockProjectShared acquires a shared lock for reading the project file in the provided path.
+// Multiple processes can hold a shared lock at the same time, but not while another process
+// holds the exclusive lock.
+func LockProjectShared(path string, wait func()) (*Lock, error) {
+	return lockProject(path, syscall.LOCK_SH, wait)
+}
+
+func lockProject(path string, how int, wait func()) (*Lock, error) {
+
+	dir := filepath.Dir(path)
+	file, err := os.Open(dir)
+	if err != nil {
+		return nil, errdefs.SystemError(err, "failed to open project directory '%s'", dir)
+	}
+
+	fd := int(file.Fd())
+	err = syscall.Flock(fd, how|syscall.LOCK_NB)
+	if errors.Is(err, syscall.EWOULDBLOCK) {
+		if wait != nil {
+			wait()
+		}
+		err = syscall.Flock(fd, how)
+	}
+	if err != nil {
+		file.Close()
+		return nil, errdefs.SystemError(err, "failed to lock project in '%s'", dir)
+	}
+
+	return &Lock{file: file}, nil
+}
+
+// Unlock releases the lock. The lock is also released when the process exits.
+func (lock *Lock) Unlock() error {
+
+	if lock.file == nil {
+		return nil
+	}
+	err := syscall.Flock(int(lock.file.Fd()), syscall.LOCK_UN)
+	lock.file.Close()
+	lock.file = nil
+	if err != nil {
+		return errdefs.SystemError(err, "failed to unlock project")
+	}
+	return nil
+}
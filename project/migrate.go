@@ -0,0 +1,123 @@
+package project
+
+import (
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/czankel/cne/errdefs"
+)
+
+// migration describes the upgrade of the project file from one version to the next version.
+// The migrate function operates on the raw project file content decoded into generic maps and
+// slices, so it can rename, move, or convert fields that no longer exist in the Project type.
+type migration struct {
+	from    string
+	to      string
+	migrate func(doc map[string]interface{}) error
+}
+
+// migrations is the registry of all project file migrations. Add an entry when changing the
+// project file format and update FileVersion to the new version.
+var migrations = []migration{{
+	// 1.1 adds the optional Base field to workspaces
+	from:    "1.0",
+	to:      "1.1",
+	migrate: func(doc map[string]interface{}) error { return nil },
+}, {
+	// 1.2 adds the optional Disabled field to layers and commands
+	from:    "1.1",
+	to:      "1.2",
+	migrate: func(doc map[string]interface{}) error { return nil },
+}, {
+	// 1.3 adds the optional Opaque field to layers
+	from:    "1.2",
+	to:      "1.3",
+	migrate: func(doc map[string]interface{}) error { return nil },
+}, {
+	// 1.4 adds the optional OriginDigest field to the environment
+	from:    "1.3",
+	to:      "1.4",
+	migrate: func(doc map[string]interface{}) error { return nil },
+}, {
+	// 1.5 adds the optional Platform field to the environment
+	from:    "1.4",
+	to:      "1.5",
+	migrate: func(doc map[string]interface{}) error { return nil },
+}}
+
+// parseVersion splits the version string in the format "major.minor" into integers.
+func parseVersion(version string) (int, int, error) {
+
+	v := strings.Split(version, ".")
+	if len(v) != 2 {
+		return 0, 0, errdefs.InvalidArgument("invalid project file version: '%s'", version)
+	}
+	major, err := strconv.Atoi(v[0])
+	if err != nil {
+		return 0, 0, errdefs.InvalidArgument("invalid project file version: '%s'", version)
+	}
+	minor, err := strconv.Atoi(v[1])
+	if err != nil {
+		return 0, 0, errdefs.InvalidArgument("invalid project file version: '%s'", version)
+	}
+	return major, minor, nil
+}
+
+// compareVersions returns -1, 0, or 1 if version 'a' is older, the same, or newer than 'b'.
+func compareVersions(a, b string) (int, error) {
+
+	aMajor, aMinor, err := parseVersion(a)
+	if err != nil {
+		return 0, err
+	}
+	bMajor, bMinor, err := parseVersion(b)
+	if err != nil {
+		return 0, err
+	}
+
+	switch {
+	case aMajor < bMajor || aMajor == bMajor && aMinor < bMinor:
+		return -1, nil
+	case aMajor > bMajor || aMajor == bMajor && aMinor > bMinor:
+		return 1, nil
+	}
+	return 0, nil
+}
+
+// migrate upgrades the project file content from the provided version to the current version
+// and returns the updated content.
+func migrate(prjStr []byte, version string) ([]byte, error) {
+
+	var doc map[string]interface{}
+	err := yaml.Unmarshal(prjStr, &doc)
+	if err != nil {
+		return nil, errdefs.InvalidArgument("project file corrupt: %v", err)
+	}
+
+	for version != FileVersion {
+		found := false
+		for _, m := range migrations {
+			if m.from == version {
+				if err := m.migrate(doc); err != nil {
+					return nil, err
+				}
+				version = m.to
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, errdefs.InvalidArgument(
+				"cannot migrate project file from version %s", version)
+		}
+	}
+
+	doc["version"] = version
+	prjStr, err = yaml.Marshal(doc)
+	if err != nil {
+		return nil, errdefs.InvalidArgument("project file corrupt: %v", err)
+	}
+	return prjStr, nil
+}
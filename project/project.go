@@ -21,9 +21,9 @@ import (
 )
 
 const (
-	ProjectFileName    = "cneproject"
-	projectFileVersion = "1.0"
-	projectFilePerm    = 0600
+	ProjectFileName = "cneproject"
+	FileVersion     = "1.5" // current version of the project file
+	projectFilePerm = 0600
 
 	WorkspaceDefaultName = "main"
 
@@ -59,48 +59,74 @@ type Project struct {
 	UUID                 string // Universal Unique id for the project
 	CurrentWorkspaceName string
 	Workspaces           []Workspace
-	Path                 string `yaml:"-"` // path to the project file
-	instanceID           uint64
-	modifiedAt           time.Time
+	Path                 string    `yaml:"-"` // path to the project file
+	instanceID           uint64    // inode of the project file when loaded or written
+	modifiedAt           time.Time // modification time when loaded or written
+	fileVersion          string
 }
 
 // Workspace is a specific environment of the project. They allow for building a development
 // pipeline by propagating results to the following workspace.
+// A workspace can inherit the layers of a base workspace and is built on top of the top-most
+// layer snapshot of the base workspace. The layers of the workspace only include the layers
+// added on top of the base workspace.
 // Note that Image cannot be changed and requires to create a new workspace
 type Workspace struct {
 	Name        string // Name of the workspace (must be unique)
+	Base        string `yaml:",omitempty"` // Name of the base workspace (optional)
 	ProjectUUID string `yaml:"-" output:"-"`
 	Environment Environment
+	project     *Project
 }
 
+// Update strategies for the packages and the origin image of the environment
+const (
+	UpdateNever  = "never"
+	UpdateManual = "manual"
+	UpdateAuto   = "auto"
+)
+
 // Environment describes the container-native environment
 // The options for the update strategy are as follows:
-//   - "never"  -  packages are never updated
-//   - "manual" -  manually (re-)building the image will update the packages
-//   - "auto"   -  packages will be updated whenever the package layer(s) are rebuild
+//   - "never"  -  packages and the origin image are never updated
+//   - "manual" -  manually (re-)building the image will update the packages, and pulling the
+//     origin image updates the image
+//   - "auto"   -  packages will be updated whenever the package layer(s) are rebuild, and the
+//     origin image is updated to the latest image in the registry when building the container
 //
-// Note that the image needs to be pulled manually to cause an update (using 'pull')
+// The origin image is pinned to the digest of the image when the workspace is created or the
+// image is pulled, and builds use the pinned image. The default strategy is "manual".
+//
+// The platform selects the image of a multi-platform origin image, for example, linux/arm64.
+// The default is the platform of the host.
 type Environment struct {
-	Origin string // Name or link of the base image
-	Update string // Update package strategy: One of "never", "manual", "auto"
-	Layers []Layer
+	Origin       string // Name or link of the base image
+	OriginDigest string `yaml:",omitempty"` // Digest the origin image is pinned to
+	Platform     string `yaml:",omitempty"` // Platform of the image in the format os/arch[/variant]
+	Update       string // Update package strategy: One of "never", "manual", "auto"
+	Layers       []Layer
 }
 
 // Layer describes an 'overlay' layer. This can be virtual or explicit using an overlay FS
 // Note that ideally we could use compositions for apt and other handlers
+// Opaque layers are snapshots of changes made directly in the container. They cannot be
+// rebuilt and the commands, if any, are only informational.
 type Layer struct {
 	Name     string // Unique name for the layer in the workspace; must not contain '/'
 	Handler  string // one of the layer handlers
-	Digest   string `output:"-"` // Images/Snaps for faster rebuilds
+	Disabled bool   `yaml:",omitempty"` // Disabled layers are skipped when building
+	Opaque   bool   `yaml:",omitempty"` // Layer is a snapshot without replayable commands
+	Digest   string `output:"-"`        // Images/Snaps for faster rebuilds
 	Commands []Command
 }
 
 // Command describes the command and its argument(s).
 // The Name is optional and used by support functions to manage the command list.
 type Command struct {
-	Name string
-	Envs []string `output:"flat" yaml:",flow"`
-	Args []string `output:"flat" yaml:",flow"`
+	Name     string
+	Envs     []string `output:"flat" yaml:",flow"`
+	Args     []string `output:"flat" yaml:",flow"`
+	Disabled bool     `yaml:",omitempty"` // Disabled commands are skipped when building
 }
 
 // Create creates the project in the provide path
@@ -157,16 +183,11 @@ func Create(name, path string) (*Project, error) {
 	}
 
 	prj := &Project{
-		Name:       name,
-		UUID:       uuid.New().String(),
-		Path:       path,
-		modifiedAt: fileInfo.ModTime(),
-	}
-
-	stat, ok := fileInfo.Sys().(*syscall.Stat_t)
-	if ok {
-		prj.instanceID = stat.Ino
+		Name: name,
+		UUID: uuid.New().String(),
+		Path: path,
 	}
+	prj.updateFileInfo(fileInfo)
 
 	err = prj.Write()
 	return prj, err
@@ -210,8 +231,18 @@ func GetProjectPath(path string) (string, error) {
 
 // Load loads the project from the provided path.
 // It also scans all parent paths for the project file if path is a directory.
+// Project files of an older version are migrated to the current version, and project files
+// of a newer version are refused.
 func Load(path string) (*Project, error) {
 
+	// stat the file before reading it, so a concurrent write is detected when writing
+	fileInfo, err := os.Stat(path)
+	if err != nil && os.IsNotExist(err) {
+		return nil, errdefs.NotFound("file", path)
+	} else if err != nil {
+		return nil, errdefs.SystemError(err, "failed to stat file '%s'", path)
+	}
+
 	prjStr, err := os.ReadFile(path)
 	if err != nil && os.IsNotExist(err) {
 		return nil, errdefs.NotFound("file", path)
@@ -225,48 +256,154 @@ func Load(path string) (*Project, error) {
 		return nil, errdefs.InvalidArgument("project file corrupt: %v", err)
 	}
 
+	cmp, err := compareVersions(header.Version, FileVersion)
+	if err != nil {
+		return nil, err
+	}
+	if cmp > 0 {
+		return nil, errdefs.InvalidArgument(
+			"project file version %s is newer than the supported version %s, "+
+				"please update cne", header.Version, FileVersion)
+	}
+	if cmp < 0 {
+		prjStr, err = migrate(prjStr, header.Version)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	var prj Project
 	err = yaml.Unmarshal(prjStr, &prj)
 	if err != nil {
 		return nil, errdefs.InvalidArgument("project file corrupt: %v", err)
 	}
 
-	fileInfo, err := os.Stat(path)
 	prj.Path = path
+	prj.fileVersion = header.Version
+	prj.updateFileInfo(fileInfo)
 
-	prj.modifiedAt = fileInfo.ModTime()
-	stat, ok := fileInfo.Sys().(*syscall.Stat_t)
-	if ok {
-		prj.instanceID = stat.Ino
-	}
 	// Fixup workspaces
 	for i := 0; i < len(prj.Workspaces); i++ {
 		prj.Workspaces[i].ProjectUUID = prj.UUID
+		prj.Workspaces[i].project = &prj
 	}
 
 	return &prj, nil
 }
 
-// Write writes the project to the project path
-func (prj *Project) Write() error {
+// FileVersion returns the version of the project file when it was loaded. The version is older
+// than the current version if the project was migrated and hasn't been written since.
+func (prj *Project) FileVersion() string {
+	if prj.fileVersion == "" {
+		return FileVersion
+	}
+	return prj.fileVersion
+}
+
+// Marshal returns the content of the project file in the current version.
+func (prj *Project) Marshal() ([]byte, error) {
 
 	header := &Header{
-		projectFileVersion,
+		FileVersion,
 	}
 	hStr, err := yaml.Marshal(header)
 	if err != nil {
-		return errdefs.InvalidArgument("project file corrupt")
+		return nil, errdefs.InvalidArgument("project file corrupt")
 	}
 
 	pStr, err := yaml.Marshal(prj)
 	if err != nil {
-		return errdefs.InvalidArgument("project file corrupt")
+		return nil, errdefs.InvalidArgument("project file corrupt")
+	}
+
+	return append(hStr, pStr...), nil
+}
+
+// updateFileInfo updates the instance ID and modification time from the project file.
+func (prj *Project) updateFileInfo(fileInfo os.FileInfo) {
+
+	prj.modifiedAt = fileInfo.ModTime()
+	stat, ok := fileInfo.Sys().(*syscall.Stat_t)
+	if ok {
+		prj.instanceID = stat.Ino
+	}
+}
+
+// isModified returns true if the project file was modified or replaced since it was loaded or
+// last written.
+func (prj *Project) isModified() bool {
+
+	fileInfo, err := os.Stat(prj.Path)
+	if err != nil {
+		return false
+	}
+	stat, ok := fileInfo.Sys().(*syscall.Stat_t)
+	if ok && stat.Ino != prj.instanceID {
+		return true
+	}
+	return !fileInfo.ModTime().Equal(prj.modifiedAt)
+}
+
+// CheckConflict returns ErrConflict if the project file was changed since it was loaded or last
+// written, for example, by another command while the project was not locked.
+func (prj *Project) CheckConflict() error {
+
+	if prj.isModified() {
+		return errdefs.Conflict("project", prj.Path)
+	}
+	return nil
+}
+
+// Write writes the project to the project path.
+// It returns ErrConflict if the project file was changed since it was loaded or last written.
+// The project file is written to a temporary file first and then atomically replaced.
+func (prj *Project) Write() error {
+
+	if err := prj.CheckConflict(); err != nil {
+		return err
+	}
+
+	prjStr, err := prj.Marshal()
+	if err != nil {
+		return err
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(prj.Path), "."+ProjectFileName+"-")
+	if err != nil {
+		return errdefs.SystemError(err, "failed to write project")
+	}
+	tmpPath := file.Name()
+	defer os.Remove(tmpPath) // no-op after the rename
+
+	err = file.Chmod(projectFilePerm)
+	if err == nil && os.Geteuid() != os.Getuid() {
+		err = file.Chown(os.Getuid(), os.Getgid())
+	}
+	if err == nil {
+		_, err = file.Write(prjStr)
+	}
+	if err == nil {
+		err = file.Sync()
+	}
+	if cerr := file.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return errdefs.SystemError(err, "failed to write project")
 	}
 
-	err = os.WriteFile(prj.Path, append(hStr, pStr...), projectFilePerm)
+	err = os.Rename(tmpPath, prj.Path)
 	if err != nil {
 		return errdefs.SystemError(err, "failed to write project")
 	}
+
+	fileInfo, err := os.Stat(prj.Path)
+	if err != nil {
+		return errdefs.SystemError(err, "failed to stat file '%s'", prj.Path)
+	}
+	prj.updateFileInfo(fileInfo)
+	prj.fileVersion = FileVersion
+
 	return nil
 }
 
@@ -314,8 +451,10 @@ func (prj *Project) SetCurrentWorkspace(name string) error {
 	return errdefs.NotFound("workspace", name)
 }
 
-func (prj *Project) UpdateWorkspace(ws *Workspace, origin string) {
+// UpdateWorkspace sets the origin image of the workspace and pins it to the provided digest.
+func (prj *Project) UpdateWorkspace(ws *Workspace, origin string, digest string) {
 	ws.Environment.Origin = origin
+	ws.Environment.OriginDigest = digest
 }
 
 // CreateWorkspace creates a new workspace in the project before the provided workspace
@@ -339,6 +478,7 @@ func (prj *Project) CreateWorkspace(name string, origin string, before string) (
 		Name:        name,
 		ProjectUUID: prj.UUID,
 		Environment: Environment{Origin: origin, Layers: []Layer{}},
+		project:     prj,
 	}
 
 	idx := len(prj.Workspaces)
@@ -360,10 +500,138 @@ func (prj *Project) CreateWorkspace(name string, origin string, before string) (
 	return &prj.Workspaces[idx], nil
 }
 
+// CloneWorkspace creates a new workspace with a copy of the environment of the provided
+// workspace before the provided workspace or at the end if 'before' is an empty string.
+// The layers of the new workspace are not built. Opaque layers keep their snapshot, as the
+// layers below are identical and are built from the build cache.
+func (prj *Project) CloneWorkspace(from *Workspace, name, before string) (*Workspace, error) {
+
+	// copy the environment first as creating the workspace can move the source workspace
+	base := from.Base
+	env := Environment{
+		Origin:       from.Environment.Origin,
+		OriginDigest: from.Environment.OriginDigest,
+		Platform:     from.Environment.Platform,
+		Update:       from.Environment.Update,
+		Layers:       make([]Layer, len(from.Environment.Layers)),
+	}
+	for i := range from.Environment.Layers {
+		env.Layers[i] = from.Environment.Layers[i].Copy()
+		if env.Layers[i].Opaque {
+			env.Layers[i].Digest = from.Environment.Layers[i].Digest
+		}
+	}
+
+	ws, err := prj.CreateWorkspace(name, env.Origin, before)
+	if err != nil {
+		return nil, err
+	}
+	ws.Base = base
+	ws.Environment = env
+
+	return ws, nil
+}
+
+// SetWorkspaceBase sets the base workspace of the provided workspace. The workspace inherits
+// the image and layers of the base workspace. Use an empty name to remove the base workspace.
+func (prj *Project) SetWorkspaceBase(ws *Workspace, name string) error {
+
+	if name == "" {
+		ws.Base = ""
+		return nil
+	}
+
+	base, err := prj.Workspace(name)
+	if err != nil {
+		return err
+	}
+
+	for b := base; b != nil; {
+		if b.Name == ws.Name {
+			return errdefs.InvalidArgument(
+				"workspace '%s' cannot be based on itself", ws.Name)
+		}
+		if b, err = b.BaseWorkspace(); err != nil {
+			return err
+		}
+	}
+
+	ws.Base = base.Name
+	ws.Environment.Origin = base.Environment.Origin
+	ws.Environment.OriginDigest = base.Environment.OriginDigest
+	ws.Environment.Platform = base.Environment.Platform
+	for i := range ws.Environment.Layers {
+		ws.Environment.Layers[i].Digest = ""
+	}
+	return nil
+}
+
+// PromoteLayers copies the specified layers, or all layers if names is empty, from one
+// workspace to another workspace. Layers with the same name are replaced, and all other
+// layers are appended. It returns the index of the first changed layer in the destination
+// workspace. Opaque layers cannot be promoted.
+func (prj *Project) PromoteLayers(from, to *Workspace, names []string) (int, error) {
+
+	for b := to; b != nil; {
+		if b.Base == from.Name {
+			return -1, errdefs.InvalidArgument(
+				"workspace '%s' already inherits the layers from '%s'", to.Name, from.Name)
+		}
+		var err error
+		if b, err = b.BaseWorkspace(); err != nil {
+			return -1, err
+		}
+	}
+
+	if len(names) == 0 {
+		for _, l := range from.Environment.Layers {
+			names = append(names, l.Name)
+		}
+	}
+
+	for _, name := range names {
+		_, layer, err := from.FindLayer(name)
+		if err != nil {
+			return -1, err
+		}
+		if layer.Opaque {
+			return -1, opaqueLayerCopyError(layer)
+		}
+	}
+
+	first := len(to.Environment.Layers)
+	for _, name := range names {
+		_, layer, _ := from.FindLayer(name)
+		newLayer := layer.Copy()
+
+		idx, _, err := to.FindLayer(name)
+		if err == nil {
+			to.Environment.Layers[idx] = newLayer
+		} else {
+			idx = len(to.Environment.Layers)
+			to.Environment.Layers = append(to.Environment.Layers, newLayer)
+		}
+		if idx < first {
+			first = idx
+		}
+	}
+
+	if first < len(to.Environment.Layers) {
+		to.UpdateLayer(&to.Environment.Layers[first])
+	}
+	return first, nil
+}
+
 // DeleteWorkspace removes the specified workspace.
 // If it was the current workspace, the current workspace will become unset
 func (prj *Project) DeleteWorkspace(name string) error {
 
+	for _, ws := range prj.Workspaces {
+		if ws.Base == name {
+			return errdefs.InUse("workspace", name)
+		}
+	}
+
 	for i, ws := range prj.Workspaces {
 		if name == ws.Name {
 			prj.Workspaces = append(prj.Workspaces[:i], prj.Workspaces[i+1:]...)
@@ -439,18 +707,71 @@ func (ws *Workspace) BaseHash() [16]byte {
 	return gen
 }
 
-// ConfigHash returns a unique hash over the Workspace Environment.
+// ConfigHash returns a unique hash over the Workspace Environment, including the environment
+// of the base workspace.
 func (ws *Workspace) ConfigHash() [16]byte {
 
 	var gen [16]byte
 
 	hashVal := md5.New()
 	hashValueElem(hashVal, "", reflect.ValueOf(ws.Environment), true /* deep */)
+	if base, err := ws.BaseWorkspace(); err == nil && base != nil {
+		baseGen := base.ConfigHash()
+		hashVal.Write(baseGen[:])
+	}
 	copy(gen[:], hashVal.Sum(nil)[:])
 
 	return gen
 }
 
+// OriginReference returns the reference of the origin image including the digest the image is
+// pinned to, or just the origin if the image isn't pinned or the origin includes a digest.
+func (ws *Workspace) OriginReference() string {
+
+	origin := ws.Environment.Origin
+	if ws.Environment.OriginDigest == "" || strings.Contains(origin, "@") {
+		return origin
+	}
+	return origin + "@" + ws.Environment.OriginDigest
+}
+
+// Project returns the project of the workspace or nil if the workspace isn't part of a project.
+func (ws *Workspace) Project() *Project {
+	return ws.project
+}
+
+// BaseWorkspace returns the base workspace or nil if the workspace doesn't have a base.
+// It returns an error if the base workspace doesn't exist or if the bases are cyclic.
+func (ws *Workspace) BaseWorkspace() (*Workspace, error) {
+
+	if ws.Base == "" {
+		return nil, nil
+	}
+	if ws.project == nil {
+		return nil, errdefs.NotFound("workspace", ws.Base)
+	}
+
+	prj := ws.project
+	base, err := prj.Workspace(ws.Base)
+	if err != nil {
+		return nil, err
+	}
+
+	b := base
+	for i := 0; b.Base != ""; i++ {
+		if i >= len(prj.Workspaces) {
+			return nil, errdefs.InvalidArgument(
+				"cyclic base workspaces for workspace '%s'", ws.Name)
+		}
+		b, err = prj.Workspace(b.Base)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return base, nil
+}
+
 // CreateLayer inserts a new layer at the provided index, or at the end if 'at' is ""
 func (ws *Workspace) CreateLayer(name string, at string) (int, *Layer, error) {
 
@@ -462,7 +783,7 @@ func (ws *Workspace) CreateLayer(name string, at string) (int, *Layer, error) {
 			}
 			atIndex = i
 		} else {
-			atIndex, _, err = ws.FindLayer(name)
+			atIndex, _, err = ws.FindLayer(at)
 			if err != nil {
 				return -1, nil, err
 			}
@@ -475,6 +796,62 @@ func (ws *Workspace) CreateLayer(name string, at string) (int, *Layer, error) {
 	return atIndex, &ws.Environment.Layers[atIndex], nil
 }
 
+// opaqueLayerCopyError returns the error for copying an opaque layer, which depends on the
+// snapshots of the layers below and cannot be rebuilt on top of other layers.
+func opaqueLayerCopyError(layer *Layer) error {
+	return errdefs.InvalidArgument(
+		"snapshot layer '%s' cannot be copied to another workspace; "+
+			"commit the changes in that workspace instead", layer.Name)
+}
+
+// CopyLayer inserts a copy of the provided layer, which can be a layer of another workspace,
+// before the layer 'at' or at the end if 'at' is an empty string. Opaque layers cannot be
+// copied.
+func (ws *Workspace) CopyLayer(layer *Layer, at string) (int, *Layer, error) {
+
+	if layer.Opaque {
+		return -1, nil, opaqueLayerCopyError(layer)
+	}
+	if _, _, err := ws.FindLayer(layer.Name); err == nil {
+		return -1, nil, errdefs.AlreadyExists("layer", layer.Name)
+	}
+
+	newLayer := layer.Copy()
+	layerIdx, l, err := ws.CreateLayer(newLayer.Name, at)
+	if err != nil {
+		return -1, nil, err
+	}
+	l.Handler = newLayer.Handler
+	l.Disabled = newLayer.Disabled
+	if err := ws.InsertCommands(l, "", newLayer.Commands); err != nil {
+		return -1, nil, err
+	}
+	ws.UpdateLayer(l)
+
+	return layerIdx, l, nil
+}
+
+// Copy returns a deep copy of the layer without the digest of the layer snapshot.
+func (layer *Layer) Copy() Layer {
+
+	newLayer := Layer{
+		Name:     layer.Name,
+		Handler:  layer.Handler,
+		Disabled: layer.Disabled,
+		Opaque:   layer.Opaque,
+		Commands: make([]Command, len(layer.Commands)),
+	}
+	for i, c := range layer.Commands {
+		newLayer.Commands[i] = Command{
+			Name:     c.Name,
+			Disabled: c.Disabled,
+			Envs:     append([]string{}, c.Envs...),
+			Args:     append([]string{}, c.Args...),
+		}
+	}
+	return newLayer
+}
+
 // FindLayer looks up the layer by name and returns the layer index starting with 0 for the first
 // layer in the list and a pointer to the Layer structure.
 // If the layer cannot be found, it returns an index value of -1 and nil for the layer.
@@ -518,6 +895,177 @@ func (ws *Workspace) DeleteLayer(name string) error {
 	return nil
 }
 
+// MoveLayer moves the specified layer before the layer 'to', which can be a layer name or
+// index, or to the end if 'to' is an empty string.
+// All layers starting with the first moved layer are invalidated.
+func (ws *Workspace) MoveLayer(name string, to string) error {
+
+	from, _, err := ws.FindLayer(name)
+	if err != nil {
+		return err
+	}
+
+	layers := ws.Environment.Layers
+	toIdx := len(layers)
+	if to != "" {
+		if i, err := strconv.Atoi(to); err == nil {
+			if i < 0 || i > len(layers) {
+				return errdefs.InvalidArgument("invalid index: %d", i)
+			}
+			toIdx = i
+		} else if toIdx, _, err = ws.FindLayer(to); err != nil {
+			return err
+		}
+	}
+
+	layer := layers[from]
+	layers = append(layers[:from], layers[from+1:]...)
+	if toIdx > from {
+		toIdx--
+	}
+	layers = append(layers[:toIdx], append([]Layer{layer}, layers[toIdx:]...)...)
+	ws.Environment.Layers = layers
+
+	first := from
+	if toIdx < first {
+		first = toIdx
+	}
+	ws.UpdateLayer(&ws.Environment.Layers[first])
+
+	return nil
+}
+
+// RenameLayer renames the specified layer.
+func (ws *Workspace) RenameLayer(name string, newName string) error {
+
+	if newName == "" || strings.Contains(newName, "/") {
+		return errdefs.InvalidArgument("invalid layer name: '%s'", newName)
+	}
+	if _, _, err := ws.FindLayer(newName); err == nil {
+		return errdefs.AlreadyExists("layer", newName)
+	}
+
+	_, layer, err := ws.FindLayer(name)
+	if err != nil {
+		return err
+	}
+	layer.Name = newName
+
+	return nil
+}
+
+// CheckLayerNames checks that the names of the layers are valid and unique.
+func CheckLayerNames(layers []Layer) error {
+
+	names := make(map[string]bool, len(layers))
+	for _, l := range layers {
+		if l.Name == "" || strings.Contains(l.Name, "/") {
+			return errdefs.InvalidArgument("invalid layer name: '%s'", l.Name)
+		}
+		if names[l.Name] {
+			return errdefs.AlreadyExists("layer", l.Name)
+		}
+		names[l.Name] = true
+	}
+	return nil
+}
+
+// CheckOpaqueLayers checks that the layers don't change or move the opaque layers of the
+// workspace, which cannot be rebuilt from their commands. Opaque layers can be removed, but
+// other layers cannot be changed to opaque layers.
+func (ws *Workspace) CheckOpaqueLayers(layers []Layer) error {
+
+	oldLayers := ws.Environment.Layers
+	for i := range layers {
+		l := &layers[i]
+		idx := -1
+		for j := range oldLayers {
+			if oldLayers[j].Name == l.Name {
+				idx = j
+				break
+			}
+		}
+		if idx < 0 || !oldLayers[idx].Opaque {
+			if l.Opaque {
+				return errdefs.InvalidArgument(
+					"layer '%s' cannot be changed to a snapshot layer", l.Name)
+			}
+			continue
+		}
+		if idx != i {
+			return errdefs.InvalidArgument("snapshot layer '%s' cannot be moved", l.Name)
+		}
+		if !l.equal(&oldLayers[idx]) {
+			return errdefs.InvalidArgument("snapshot layer '%s' cannot be changed", l.Name)
+		}
+	}
+	return nil
+}
+
+// SetLayers replaces all layers of the workspace. The snapshots of the unchanged layers are kept
+// and all layers starting with the first changed layer are invalidated.
+func (ws *Workspace) SetLayers(layers []Layer) error {
+
+	if err := CheckLayerNames(layers); err != nil {
+		return err
+	}
+
+	oldLayers := ws.Environment.Layers
+	first := len(layers)
+	for i := range layers {
+		layers[i].Digest = ""
+		if i < len(oldLayers) {
+			layers[i].Digest = oldLayers[i].Digest
+		}
+		if i < first && (i >= len(oldLayers) || !layers[i].equal(&oldLayers[i])) {
+			first = i
+		}
+	}
+
+	ws.Environment.Layers = layers
+	if first < len(layers) {
+		ws.UpdateLayer(&layers[first])
+	}
+	return nil
+}
+
+// SetLayerCommands replaces all commands of the layer and invalidates the layer and following
+// layers if any command has changed.
+func (ws *Workspace) SetLayerCommands(layer *Layer, commands []Command) {
+
+	newLayer := *layer
+	newLayer.Commands = commands
+	if !layer.equal(&newLayer) {
+		layer.Commands = commands
+		ws.UpdateLayer(layer)
+	}
+}
+
+// equal compares the definitions of the layers, excluding the layer snapshot.
+func (layer *Layer) equal(other *Layer) bool {
+
+	if layer.Name != other.Name || layer.Handler != other.Handler ||
+		layer.Disabled != other.Disabled || layer.Opaque != other.Opaque ||
+		len(layer.Commands) != len(other.Commands) {
+		return false
+	}
+	for i := range layer.Commands {
+		if !layer.Commands[i].equal(&other.Commands[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// DisableLayer disables or enables the layer and invalidates the layer and following layers.
+func (ws *Workspace) DisableLayer(layer *Layer, disable bool) {
+
+	if layer.Disabled != disable {
+		layer.Disabled = disable
+		ws.UpdateLayer(layer)
+	}
+}
+
 // TopLayer returns the pointer to the top layer.
 func (ws *Workspace) TopLayer() *Layer {
 	cnt := len(ws.Environment.Layers)
@@ -529,6 +1077,8 @@ func (ws *Workspace) TopLayer() *Layer {
 }
 
 // UpdateLayer ensures that any cached or reference data is updated
+// Opaque layers following the layer keep their snapshot, as they cannot be rebuilt. Building
+// the workspace reports a conflict if the snapshot wasn't committed on the rebuilt layers.
 func (ws *Workspace) UpdateLayer(layer *Layer) {
 
 	invalidate := false
@@ -536,13 +1086,33 @@ func (ws *Workspace) UpdateLayer(layer *Layer) {
 		l := &ws.Environment.Layers[i]
 		if l.Name == layer.Name {
 			invalidate = true
-		}
-		if invalidate {
+			l.Digest = ""
+		} else if invalidate && !l.Opaque {
 			l.Digest = ""
 		}
 	}
 }
 
+// equal compares the commands treating nil and empty slices the same.
+func (cmd *Command) equal(other *Command) bool {
+
+	if cmd.Name != other.Name || cmd.Disabled != other.Disabled ||
+		len(cmd.Envs) != len(other.Envs) || len(cmd.Args) != len(other.Args) {
+		return false
+	}
+	for i := range cmd.Envs {
+		if cmd.Envs[i] != other.Envs[i] {
+			return false
+		}
+	}
+	for i := range cmd.Args {
+		if cmd.Args[i] != other.Args[i] {
+			return false
+		}
+	}
+	return true
+}
+
 func getCommandIndex(layer *Layer, at string) (int, error) {
 
 	if at == "" {
@@ -590,6 +1160,26 @@ func (ws *Workspace) UpdateCommands(layer *Layer, at string, commands Command) e
 	return nil
 }
 
+// DisableCommand disables or enables the command at the provided index or with the provided
+// name and invalidates the layer and following layers.
+func (ws *Workspace) DisableCommand(layer *Layer, at string, disable bool) error {
+
+	atIndex, err := getCommandIndex(layer, at)
+	if err != nil {
+		return err
+	}
+	if atIndex >= len(layer.Commands) {
+		return errdefs.InvalidArgument("no command entry specified")
+	}
+
+	if layer.Commands[atIndex].Disabled != disable {
+		layer.Commands[atIndex].Disabled = disable
+		ws.UpdateLayer(layer)
+	}
+
+	return nil
+}
+
 func (ws *Workspace) RemoveCommands(layer *Layer, at string) error {
 
 	atIndex, err := getCommandIndex(layer, at)
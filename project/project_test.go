@@ -513,3 +513,807 @@ func TestProjectLayers(t *testing.T) {
 		t.Fatalf("Number of layers should be 0")
 	}
 }
+
+func TestProjectBaseWorkspace(t *testing.T) {
+
+	dir, err := os.MkdirTemp("", testDir)
+	if err != nil {
+		t.Fatalf("Failed to create a temporary directory")
+	}
+	defer os.RemoveAll(dir)
+
+	prj, err := Create("test", dir)
+	if err != nil {
+		t.Fatalf("Failed to create new project: %v", err)
+	}
+
+	_, err = prj.CreateWorkspace("dev", "image", "")
+	if err != nil {
+		t.Fatalf("Failed to add workspace dev: %v", err)
+	}
+	_, err = prj.CreateWorkspace("test", "other", "")
+	if err != nil {
+		t.Fatalf("Failed to add workspace test: %v", err)
+	}
+	dev, _ := prj.Workspace("dev")
+	test, _ := prj.Workspace("test")
+
+	_, layer, err := dev.CreateLayer("tools", "")
+	if err != nil {
+		t.Fatalf("Failed to create layer: %v", err)
+	}
+	layer.Commands = []Command{{Name: "make", Args: []string{"make"}}}
+	layer.Digest = "sha256:1234"
+
+	_, layer, err = test.CreateLayer("tests", "")
+	if err != nil {
+		t.Fatalf("Failed to create layer: %v", err)
+	}
+	layer.Digest = "sha256:5678"
+
+	base, err := test.BaseWorkspace()
+	if err != nil || base != nil {
+		t.Fatalf("Workspace without base should not return a base: %v", err)
+	}
+
+	hash := test.ConfigHash()
+	err = prj.SetWorkspaceBase(test, "dev")
+	if err != nil {
+		t.Fatalf("Failed to set base workspace: %v", err)
+	}
+	if test.Environment.Origin != "image" {
+		t.Errorf("Workspace should have inherited the origin from the base")
+	}
+	if test.Environment.Layers[0].Digest != "" {
+		t.Errorf("Setting the base should have invalidated the layers")
+	}
+	base, err = test.BaseWorkspace()
+	if err != nil || base == nil || base.Name != "dev" {
+		t.Fatalf("Base workspace should be dev: %v", err)
+	}
+	if test.ConfigHash() == hash {
+		t.Errorf("ConfigHash should have changed with the base workspace")
+	}
+
+	hash = test.ConfigHash()
+	dev.UpdateLayer(&dev.Environment.Layers[0])
+	dev.Environment.Layers[0].Commands[0].Args = []string{"make", "all"}
+	if test.ConfigHash() == hash {
+		t.Errorf("ConfigHash should have changed with the base layers")
+	}
+
+	err = prj.SetWorkspaceBase(dev, "test")
+	if !errors.Is(err, errdefs.ErrInvalidArgument) {
+		t.Errorf("Cyclic base workspaces should have failed: %v", err)
+	}
+	err = prj.SetWorkspaceBase(dev, "unknown")
+	if !errors.Is(err, errdefs.ErrNotFound) {
+		t.Errorf("Unknown base workspace should have failed: %v", err)
+	}
+
+	err = prj.DeleteWorkspace("dev")
+	if !errors.Is(err, errdefs.ErrInUse) {
+		t.Errorf("Deleting a base workspace should have failed: %v", err)
+	}
+
+	_, err = prj.PromoteLayers(dev, test, nil)
+	if !errors.Is(err, errdefs.ErrInvalidArgument) {
+		t.Errorf("Promoting layers to a derived workspace should have failed: %v", err)
+	}
+
+	err = prj.SetWorkspaceBase(test, "")
+	if err != nil {
+		t.Fatalf("Failed to remove base workspace: %v", err)
+	}
+	idx, err := prj.PromoteLayers(dev, test, nil)
+	if err != nil {
+		t.Fatalf("Failed to promote layers: %v", err)
+	}
+	if idx != 1 || len(test.Environment.Layers) != 2 {
+		t.Fatalf("Promoted layer should have been appended")
+	}
+	promoted := test.Environment.Layers[1]
+	if promoted.Name != "tools" || promoted.Digest != "" ||
+		len(promoted.Commands) != 1 || promoted.Commands[0].Args[1] != "all" {
+		t.Errorf("Promoted layer mismatch: %v", promoted)
+	}
+	promoted.Commands[0].Args[0] = "changed"
+	if dev.Environment.Layers[0].Commands[0].Args[0] != "make" {
+		t.Errorf("Promoted layer should be a copy")
+	}
+
+	idx, err = prj.PromoteLayers(dev, test, []string{"tools"})
+	if err != nil || idx != 1 || len(test.Environment.Layers) != 2 {
+		t.Errorf("Promoting an existing layer should have replaced it: %v", err)
+	}
+	_, err = prj.PromoteLayers(dev, test, []string{"unknown"})
+	if !errors.Is(err, errdefs.ErrNotFound) {
+		t.Errorf("Promoting an unknown layer should have failed: %v", err)
+	}
+
+	err = prj.DeleteWorkspace("dev")
+	if err != nil {
+		t.Errorf("Deleting an unused workspace should have succeeded: %v", err)
+	}
+}
+
+func TestProjectCloneWorkspace(t *testing.T) {
+
+	dir, err := os.MkdirTemp("", testDir)
+	if err != nil {
+		t.Fatalf("Failed to create a temporary directory")
+	}
+	defer os.RemoveAll(dir)
+
+	prj, err := Create("test", dir)
+	if err != nil {
+		t.Fatalf("Failed to create new project: %v", err)
+	}
+
+	_, err = prj.CreateWorkspace("dev", "image", "")
+	if err != nil {
+		t.Fatalf("Failed to add workspace dev: %v", err)
+	}
+	dev, _ := prj.Workspace("dev")
+	for _, name := range []string{"os", "tools"} {
+		_, layer, err := dev.CreateLayer(name, "")
+		if err != nil {
+			t.Fatalf("Failed to create layer %s: %v", name, err)
+		}
+		layer.Commands = []Command{{Name: name, Args: []string{"install", name}}}
+		layer.Digest = "sha256:" + name
+	}
+
+	_, err = prj.CloneWorkspace(dev, "dev", "")
+	if !errors.Is(err, errdefs.ErrAlreadyExists) {
+		t.Errorf("Cloning to an existing workspace should have failed: %v", err)
+	}
+
+	clone, err := prj.CloneWorkspace(dev, "clone", "dev")
+	if err != nil {
+		t.Fatalf("Failed to clone workspace: %v", err)
+	}
+	if prj.Workspaces[0].Name != "clone" {
+		t.Errorf("Cloned workspace should have been inserted before dev")
+	}
+	dev, _ = prj.Workspace("dev")
+	if clone.Environment.Origin != "image" || len(clone.Environment.Layers) != 2 {
+		t.Fatalf("Cloned workspace mismatch: %v", clone.Environment)
+	}
+	for i, l := range clone.Environment.Layers {
+		if l.Digest != "" {
+			t.Errorf("Cloned layer %s should not have a digest", l.Name)
+		}
+		if l.Name != dev.Environment.Layers[i].Name {
+			t.Errorf("Cloned layer %s should be %s", l.Name, dev.Environment.Layers[i].Name)
+		}
+	}
+	clone.Environment.Layers[0].Commands[0].Args[0] = "remove"
+	if dev.Environment.Layers[0].Commands[0].Args[0] != "install" {
+		t.Errorf("Cloned layers should be a copy")
+	}
+
+	_, _, err = clone.CopyLayer(&dev.Environment.Layers[1], "")
+	if !errors.Is(err, errdefs.ErrAlreadyExists) {
+		t.Errorf("Copying an existing layer should have failed: %v", err)
+	}
+
+	_, layer, err := dev.CreateLayer("extra", "")
+	if err != nil {
+		t.Fatalf("Failed to create layer: %v", err)
+	}
+	layer.Commands = []Command{{Args: []string{"extra"}}}
+	clone.Environment.Layers[1].Digest = "sha256:tools"
+
+	idx, layer, err := clone.CopyLayer(layer, "tools")
+	if err != nil {
+		t.Fatalf("Failed to copy layer: %v", err)
+	}
+	if idx != 1 || layer.Name != "extra" || len(layer.Commands) != 1 {
+		t.Errorf("Copied layer should have been inserted before tools: %d", idx)
+	}
+	if clone.Environment.Layers[2].Digest != "" {
+		t.Errorf("Copying a layer should have invalidated the following layers")
+	}
+
+	_, layer, err = dev.CreateLayer("disabled", "")
+	if err != nil {
+		t.Fatalf("Failed to create layer: %v", err)
+	}
+	layer.Disabled = true
+	_, layer, err = clone.CopyLayer(layer, "")
+	if err != nil || !layer.Disabled {
+		t.Errorf("Copied layer should have been disabled: %v", err)
+	}
+
+	// opaque layers keep their snapshot when cloned but cannot be copied or promoted
+	_, layer, err = dev.CreateLayer("snap", "")
+	if err != nil {
+		t.Fatalf("Failed to create layer: %v", err)
+	}
+	layer.Opaque = true
+	layer.Digest = "sha256:snap"
+	_, _, err = clone.CopyLayer(layer, "")
+	if !errors.Is(err, errdefs.ErrInvalidArgument) {
+		t.Errorf("Copying an opaque layer should have failed: %v", err)
+	}
+	_, err = prj.PromoteLayers(dev, clone, []string{"snap"})
+	if !errors.Is(err, errdefs.ErrInvalidArgument) {
+		t.Errorf("Promoting an opaque layer should have failed: %v", err)
+	}
+	if _, _, err := clone.FindLayer("snap"); err == nil {
+		t.Errorf("Failed promotion should not have changed the workspace")
+	}
+
+	clone, err = prj.CloneWorkspace(dev, "clone2", "")
+	if err != nil {
+		t.Fatalf("Failed to clone workspace: %v", err)
+	}
+	_, layer, _ = clone.FindLayer("snap")
+	if layer == nil || !layer.Opaque || layer.Digest != "sha256:snap" {
+		t.Errorf("Cloned opaque layer should have kept its snapshot: %v", layer)
+	}
+	if clone.Environment.Layers[0].Digest != "" {
+		t.Errorf("Cloned layers should not have a digest")
+	}
+}
+
+func TestProjectOriginDigest(t *testing.T) {
+
+	dir, err := os.MkdirTemp("", testDir)
+	if err != nil {
+		t.Fatalf("Failed to create a temporary directory")
+	}
+	defer os.RemoveAll(dir)
+
+	prj, err := Create("test", dir)
+	if err != nil {
+		t.Fatalf("Failed to create new project: %v", err)
+	}
+
+	dev, err := prj.CreateWorkspace("dev", "", "")
+	if err != nil {
+		t.Fatalf("Failed to add workspace dev: %v", err)
+	}
+	prj.UpdateWorkspace(dev, "image:latest", "")
+	if ref := dev.OriginReference(); ref != "image:latest" {
+		t.Errorf("Unpinned origin reference should be the origin: %s", ref)
+	}
+	genUnpinned := dev.ConfigHash()
+
+	prj.UpdateWorkspace(dev, "image:latest", "sha256:1234")
+	if ref := dev.OriginReference(); ref != "image:latest@sha256:1234" {
+		t.Errorf("Pinned origin reference mismatch: %s", ref)
+	}
+	if dev.ConfigHash() == genUnpinned {
+		t.Errorf("Pinning the origin should change the generation")
+	}
+
+	clone, err := prj.CloneWorkspace(dev, "clone", "")
+	if err != nil {
+		t.Fatalf("Failed to clone workspace: %v", err)
+	}
+	if clone.Environment.OriginDigest != "sha256:1234" {
+		t.Errorf("Cloned workspace should be pinned: %s", clone.Environment.OriginDigest)
+	}
+
+	err = prj.Write()
+	if err != nil {
+		t.Fatalf("Failed to write project: %v", err)
+	}
+	prj, err = Load(prj.Path)
+	if err != nil {
+		t.Fatalf("Failed to load project: %v", err)
+	}
+	dev, _ = prj.Workspace("dev")
+	if dev.Environment.OriginDigest != "sha256:1234" {
+		t.Errorf("Pinned digest not loaded: %s", dev.Environment.OriginDigest)
+	}
+
+	prj.UpdateWorkspace(dev, "image@sha256:5678", "sha256:1234")
+	if ref := dev.OriginReference(); ref != "image@sha256:5678" {
+		t.Errorf("Origin with digest should not be pinned again: %s", ref)
+	}
+}
+
+func TestProjectUpdateLayers(t *testing.T) {
+
+	dir, err := os.MkdirTemp("", testDir)
+	if err != nil {
+		t.Fatalf("Failed to create a temporary directory")
+	}
+	defer os.RemoveAll(dir)
+
+	prj, err := Create("test", dir)
+	if err != nil {
+		t.Fatalf("Failed to create new project: %v", err)
+	}
+
+	ws, err := prj.CreateWorkspace("ws0", "image", "")
+	if err != nil {
+		t.Fatalf("Failed to add workspace: %v", err)
+	}
+
+	names := []string{"l0", "l1", "l2", "l3"}
+	resetDigests := func() {
+		for i := range ws.Environment.Layers {
+			ws.Environment.Layers[i].Digest = "sha256:" + ws.Environment.Layers[i].Name
+		}
+	}
+	checkLayers := func(names []string, digests []bool) {
+		t.Helper()
+		for i, l := range ws.Environment.Layers {
+			if l.Name != names[i] {
+				t.Errorf("Layer %d should be %s: %s", i, names[i], l.Name)
+			}
+			if (l.Digest != "") != digests[i] {
+				t.Errorf("Layer %s digest should be set: %v", l.Name, digests[i])
+			}
+		}
+	}
+
+	for _, name := range names {
+		_, layer, err := ws.CreateLayer(name, "")
+		if err != nil {
+			t.Fatalf("Failed to create layer %s: %v", name, err)
+		}
+		layer.Commands = []Command{{Name: "c0"}, {Name: "c1"}}
+	}
+	resetDigests()
+
+	err = ws.MoveLayer("l2", "l0")
+	if err != nil {
+		t.Fatalf("Failed to move layer: %v", err)
+	}
+	checkLayers([]string{"l2", "l0", "l1", "l3"}, []bool{false, false, false, false})
+	resetDigests()
+
+	err = ws.MoveLayer("l0", "")
+	if err != nil {
+		t.Fatalf("Failed to move layer: %v", err)
+	}
+	checkLayers([]string{"l2", "l1", "l3", "l0"}, []bool{true, false, false, false})
+	resetDigests()
+
+	err = ws.MoveLayer("l3", "4")
+	if err != nil {
+		t.Fatalf("Failed to move layer: %v", err)
+	}
+	checkLayers([]string{"l2", "l1", "l0", "l3"}, []bool{true, true, false, false})
+
+	err = ws.MoveLayer("l3", "5")
+	if !errors.Is(err, errdefs.ErrInvalidArgument) {
+		t.Errorf("Moving a layer to an invalid index should have failed: %v", err)
+	}
+	err = ws.MoveLayer("l5", "")
+	if !errors.Is(err, errdefs.ErrNotFound) {
+		t.Errorf("Moving an unknown layer should have failed: %v", err)
+	}
+
+	resetDigests()
+	err = ws.RenameLayer("l1", "l2")
+	if !errors.Is(err, errdefs.ErrAlreadyExists) {
+		t.Errorf("Renaming a layer to an existing name should have failed: %v", err)
+	}
+	err = ws.RenameLayer("l1", "a/b")
+	if !errors.Is(err, errdefs.ErrInvalidArgument) {
+		t.Errorf("Renaming a layer to an invalid name should have failed: %v", err)
+	}
+	err = ws.RenameLayer("l1", "new")
+	if err != nil {
+		t.Fatalf("Failed to rename layer: %v", err)
+	}
+	checkLayers([]string{"l2", "new", "l0", "l3"}, []bool{true, true, true, true})
+
+	_, layer, _ := ws.FindLayer("l0")
+	ws.DisableLayer(layer, true)
+	if !layer.Disabled {
+		t.Errorf("Layer should have been disabled")
+	}
+	checkLayers([]string{"l2", "new", "l0", "l3"}, []bool{true, true, false, false})
+	resetDigests()
+
+	ws.DisableLayer(layer, true)
+	checkLayers([]string{"l2", "new", "l0", "l3"}, []bool{true, true, true, true})
+
+	_, layer, _ = ws.FindLayer("new")
+	err = ws.DisableCommand(layer, "c1", true)
+	if err != nil || !layer.Commands[1].Disabled {
+		t.Fatalf("Failed to disable command: %v", err)
+	}
+	checkLayers([]string{"l2", "new", "l0", "l3"}, []bool{true, false, false, false})
+
+	err = ws.DisableCommand(layer, "2", true)
+	if !errors.Is(err, errdefs.ErrInvalidArgument) {
+		t.Errorf("Disabling an invalid command should have failed: %v", err)
+	}
+
+	copied := layer.Copy()
+	if !copied.Commands[1].Disabled {
+		t.Errorf("Copied command should be disabled")
+	}
+
+	err = ws.DisableCommand(layer, "1", false)
+	if err != nil || layer.Commands[1].Disabled {
+		t.Errorf("Failed to enable command: %v", err)
+	}
+}
+
+func TestProjectSetLayers(t *testing.T) {
+
+	dir, err := os.MkdirTemp("", testDir)
+	if err != nil {
+		t.Fatalf("Failed to create a temporary directory")
+	}
+	defer os.RemoveAll(dir)
+
+	prj, err := Create("test", dir)
+	if err != nil {
+		t.Fatalf("Failed to create new project: %v", err)
+	}
+
+	ws, err := prj.CreateWorkspace("ws0", "image", "")
+	if err != nil {
+		t.Fatalf("Failed to add workspace: %v", err)
+	}
+
+	for _, name := range []string{"l0", "l1", "l2"} {
+		_, layer, err := ws.CreateLayer(name, "")
+		if err != nil {
+			t.Fatalf("Failed to create layer %s: %v", name, err)
+		}
+		layer.Commands = []Command{{Args: []string{"echo", name}}}
+		layer.Digest = "sha256:" + name
+	}
+
+	// edited layers don't include the digest and may use empty slices
+	layers := make([]Layer, len(ws.Environment.Layers))
+	for i := range ws.Environment.Layers {
+		layers[i] = ws.Environment.Layers[i].Copy()
+		layers[i].Commands[0].Envs = []string{}
+	}
+	layers[2].Commands[0].Args = []string{"echo", "changed"}
+
+	err = ws.SetLayers(layers)
+	if err != nil {
+		t.Fatalf("Failed to set layers: %v", err)
+	}
+	l := ws.Environment.Layers
+	if l[0].Digest == "" || l[1].Digest == "" || l[2].Digest != "" {
+		t.Errorf("Only the changed layer should have been invalidated")
+	}
+
+	err = ws.SetLayers([]Layer{{Name: "l0"}, {Name: "l0"}})
+	if !errors.Is(err, errdefs.ErrAlreadyExists) {
+		t.Errorf("Duplicate layer names should have failed: %v", err)
+	}
+	err = ws.SetLayers([]Layer{{Name: ""}})
+	if !errors.Is(err, errdefs.ErrInvalidArgument) {
+		t.Errorf("Empty layer names should have failed: %v", err)
+	}
+
+	err = ws.SetLayers([]Layer{l[1].Copy(), l[0].Copy()})
+	if err != nil {
+		t.Fatalf("Failed to set layers: %v", err)
+	}
+	if len(ws.Environment.Layers) != 2 || ws.Environment.Layers[0].Digest != "" {
+		t.Errorf("Reordered layers should have been invalidated")
+	}
+
+	for i := range ws.Environment.Layers {
+		ws.Environment.Layers[i].Digest = "sha256:" + ws.Environment.Layers[i].Name
+	}
+	layer := &ws.Environment.Layers[0]
+	ws.SetLayerCommands(layer, []Command{{Envs: []string{}, Args: []string{"echo", "l1"}}})
+	if layer.Digest == "" {
+		t.Errorf("Unchanged commands should not have invalidated the layer")
+	}
+	ws.SetLayerCommands(layer, []Command{{Args: []string{"echo", "other"}}})
+	if layer.Digest != "" || ws.Environment.Layers[1].Digest != "" {
+		t.Errorf("Changed commands should have invalidated the layers")
+	}
+
+	layer.Digest = "sha256:l1"
+	layers = []Layer{layer.Copy()}
+	layers[0].Opaque = true
+	err = ws.SetLayers(layers)
+	if err != nil || ws.Environment.Layers[0].Digest != "" {
+		t.Errorf("Changing a layer to opaque should have invalidated the layer: %v", err)
+	}
+
+	_, snap, err := ws.CreateLayer("snap", "")
+	if err != nil {
+		t.Fatalf("Failed to create layer: %v", err)
+	}
+	snap.Opaque = true
+	snap.Digest = "sha256:snap"
+	layer = &ws.Environment.Layers[0]
+	layer.Digest = "sha256:l1"
+	ws.UpdateLayer(layer)
+	if layer.Digest != "" || snap.Digest != "sha256:snap" {
+		t.Errorf("Updating a layer should have kept the snapshot of the opaque layer")
+	}
+}
+
+func TestProjectVersion(t *testing.T) {
+
+	dir, err := os.MkdirTemp("", testDir)
+	if err != nil {
+		t.Fatalf("Failed to create a temporary directory")
+	}
+	defer os.RemoveAll(dir)
+
+	path := dir + "/" + ProjectFileName
+
+	// newer project files must be refused
+	err = os.WriteFile(path, []byte("version: \"99.0\"\nname: test\n"), projectFilePerm)
+	if err != nil {
+		t.Fatalf("Failed to write project file: %v", err)
+	}
+	_, err = Load(path)
+	if !errors.Is(err, errdefs.ErrInvalidArgument) {
+		t.Fatalf("Loading a newer project file should have failed: %v", err)
+	}
+
+	// older project files without a migration must be refused
+	err = os.WriteFile(path, []byte("version: \"0.9\"\nname: test\n"), projectFilePerm)
+	if err != nil {
+		t.Fatalf("Failed to write project file: %v", err)
+	}
+	_, err = Load(path)
+	if !errors.Is(err, errdefs.ErrInvalidArgument) {
+		t.Fatalf("Loading a project file without migration should have failed: %v", err)
+	}
+
+	// migrate the renamed field 'title' to 'name'
+	oldMigrations := migrations
+	defer func() { migrations = oldMigrations }()
+	migrations = append(migrations, migration{
+		from: "0.9",
+		to:   FileVersion,
+		migrate: func(doc map[string]interface{}) error {
+			doc["name"] = doc["title"]
+			delete(doc, "title")
+			return nil
+		},
+	})
+
+	err = os.WriteFile(path, []byte("version: \"0.9\"\ntitle: test\n"), projectFilePerm)
+	if err != nil {
+		t.Fatalf("Failed to write project file: %v", err)
+	}
+	prj, err := Load(path)
+	if err != nil {
+		t.Fatalf("Failed to load and migrate project: %v", err)
+	}
+	if prj.Name != "test" {
+		t.Errorf("Migrated project name should be 'test': '%s'", prj.Name)
+	}
+	if prj.FileVersion() != "0.9" {
+		t.Errorf("File version should be 0.9 before writing: %s", prj.FileVersion())
+	}
+
+	err = prj.Write()
+	if err != nil {
+		t.Fatalf("Failed to write project: %v", err)
+	}
+	if prj.FileVersion() != FileVersion {
+		t.Errorf("File version should be %s after writing: %s",
+			FileVersion, prj.FileVersion())
+	}
+
+	prj, err = Load(path)
+	if err != nil {
+		t.Fatalf("Failed to load migrated project: %v", err)
+	}
+	if prj.Name != "test" || prj.FileVersion() != FileVersion {
+		t.Errorf("Migrated project not written correctly")
+	}
+}
+
+func TestProjectWriteConflict(t *testing.T) {
+
+	dir, err := os.MkdirTemp("", testDir)
+	if err != nil {
+		t.Fatalf("Failed to create a temporary directory")
+	}
+	defer os.RemoveAll(dir)
+
+	prj, err := Create("test", dir)
+	if err != nil {
+		t.Fatalf("Failed to create new project: %v", err)
+	}
+
+	prj1, err := Load(prj.Path)
+	if err != nil {
+		t.Fatalf("Failed to load project: %v", err)
+	}
+	prj2, err := Load(prj.Path)
+	if err != nil {
+		t.Fatalf("Failed to load project: %v", err)
+	}
+
+	_, err = prj1.CreateWorkspace("ws1", "image1", "")
+	if err != nil {
+		t.Fatalf("Failed to add workspace: %v", err)
+	}
+	err = prj1.Write()
+	if err != nil {
+		t.Fatalf("Failed to write project: %v", err)
+	}
+
+	// prj1 can be written again after it was written
+	err = prj1.Write()
+	if err != nil {
+		t.Fatalf("Failed to write project again: %v", err)
+	}
+
+	_, err = prj2.CreateWorkspace("ws2", "image2", "")
+	if err != nil {
+		t.Fatalf("Failed to add workspace: %v", err)
+	}
+	err = prj2.CheckConflict()
+	if !errors.Is(err, errdefs.ErrConflict) {
+		t.Errorf("Changed project should have been reported as a conflict: %v", err)
+	}
+	err = prj2.Write()
+	if !errors.Is(err, errdefs.ErrConflict) {
+		t.Fatalf("Writing a concurrently modified project should have failed: %v", err)
+	}
+
+	prjChk, err := Load(prj.Path)
+	if err != nil {
+		t.Fatalf("Failed to load project: %v", err)
+	}
+	if len(prjChk.Workspaces) != 1 || prjChk.Workspaces[0].Name != "ws1" {
+		t.Errorf("Project should only include workspace 'ws1'")
+	}
+
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("Failed to read project directory: %v", err)
+	}
+	if len(files) != 1 {
+		t.Errorf("Temporary project files should have been removed")
+	}
+}
+
+func TestProjectLock(t *testing.T) {
+
+	dir, err := os.MkdirTemp("", testDir)
+	if err != nil {
+		t.Fatalf("Failed to create a temporary directory")
+	}
+	defer os.RemoveAll(dir)
+
+	path := dir + "/" + ProjectFileName
+
+	lock1, err := LockProject(path, nil)
+	if err != nil {
+		t.Fatalf("Failed to lock project: %v", err)
+	}
+
+	waiting := make(chan bool, 1)
+	locked := make(chan *Lock)
+	go func() {
+		lock2, err := LockProject(path, func() { waiting <- true })
+		if err != nil {
+			t.Errorf("Failed to lock project: %v", err)
+		}
+		locked <- lock2
+	}()
+
+	select {
+	case <-waiting:
+	case <-locked:
+		t.Fatalf("Second lock should have been blocked")
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Second lock should have waited for the first lock")
+	}
+
+	err = lock1.Unlock()
+	if err != nil {
+		t.Fatalf("Failed to unlock project: %v", err)
+	}
+
+	select {
+	case lock2 := <-locked:
+		if lock2 != nil {
+			lock2.Unlock()
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Second lock should have been acquired")
+	}
+}
+
+func TestProjectLockShared(t *testing.T) {
+
+	dir, err := os.MkdirTemp("", testDir)
+	if err != nil {
+		t.Fatalf("Failed to create a temporary directory")
+	}
+	defer os.RemoveAll(dir)
+
+	path := dir + "/" + ProjectFileName
+
+	shared1, err := LockProjectShared(path, nil)
+	if err != nil {
+		t.Fatalf("Failed to lock project: %v", err)
+	}
+	shared2, err := LockProjectShared(path, func() {
+		t.Errorf("Shared lock should not wait for another shared lock")
+	})
+	if err != nil {
+		t.Fatalf("Failed to lock project: %v", err)
+	}
+
+	waiting := make(chan bool, 1)
+	locked := make(chan *Lock)
+	go func() {
+		lock, err := LockProject(path, func() { waiting <- true })
+		if err != nil {
+			t.Errorf("Failed to lock project: %v", err)
+		}
+		locked <- lock
+	}()
+
+	select {
+	case <-waiting:
+	case <-locked:
+		t.Fatalf("Exclusive lock should have been blocked")
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Exclusive lock should have waited for the shared locks")
+	}
+
+	shared1.Unlock()
+	shared2.Unlock()
+
+	select {
+	case lock := <-locked:
+		if lock != nil {
+			lock.Unlock()
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Exclusive lock should have been acquired")
+	}
+}
+
+func TestProjectCheckOpaqueLayers(t *testing.T) {
+
+	ws := &Workspace{
+		Environment: Environment{
+			Layers: []Layer{
+				{Name: "l0", Digest: "sha256:l0",
+					Commands: []Command{{Args: []string{"echo", "l0"}}}},
+				{Name: "snap", Opaque: true, Digest: "sha256:snap"},
+				{Name: "l1", Digest: "sha256:l1"},
+			},
+		},
+	}
+	l := ws.Environment.Layers
+
+	// edited layers don't include the digest
+	layers := []Layer{l[0].Copy(), l[1].Copy(), l[2].Copy()}
+	layers[0].Commands = []Command{{Args: []string{"echo", "new"}}}
+	if err := ws.CheckOpaqueLayers(layers); err != nil {
+		t.Errorf("Changing other layers should have succeeded: %v", err)
+	}
+	if err := ws.CheckOpaqueLayers([]Layer{l[0], l[2]}); err != nil {
+		t.Errorf("Removing the opaque layer should have succeeded: %v", err)
+	}
+
+	changed := l[1]
+	changed.Commands = []Command{{Args: []string{"echo", "snap"}}}
+	for _, layers := range [][]Layer{
+		{l[0], changed, l[2]},
+		{l[1], l[0], l[2]},
+		{l[1], l[2]},
+		{l[0], l[1], {Name: "l1", Opaque: true}},
+		{l[0], l[1], l[2], {Name: "new", Opaque: true}},
+	} {
+		err := ws.CheckOpaqueLayers(layers)
+		if !errors.Is(err, errdefs.ErrInvalidArgument) {
+			t.Errorf("Changing opaque layers should have failed: %v", err)
+		}
+	}
+}
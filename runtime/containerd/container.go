@@ -19,6 +19,7 @@ import (
 	ctrderr "github.com/containerd/containerd/errdefs"
 	"github.com/containerd/containerd/namespaces"
 	"github.com/containerd/containerd/oci"
+	"github.com/containerd/containerd/platforms"
 	"github.com/containerd/typeurl"
 
 	runspecs "github.com/opencontainers/runtime-spec/specs-go"
@@ -35,6 +36,9 @@ type container struct {
 	generation    [16]byte
 	uid           uint32
 	spec          runspecs.Spec
+	labels        map[string]string
+	createdAt     time.Time
+	updatedAt     time.Time
 	ctrdRuntime   *containerdRuntime
 	ctrdContainer containerd.Container
 }
@@ -167,6 +171,7 @@ func getContainers(ctx context.Context,
 		}
 
 		ctr := newContainer(ctrdRun, c, dom, id, gen, uid, spec)
+		err = ctr.updateInfo(ctx)
 		if err != nil {
 			return nil, err
 		}
@@ -191,6 +196,27 @@ func newContainer(ctrdRun *containerdRuntime, ctrdCtr containerd.Container,
 	}
 }
 
+// updateInfo updates the labels and timestamps of the container from the containerd metadata.
+func (ctr *container) updateInfo(ctx context.Context) error {
+
+	info, err := ctr.ctrdContainer.Info(ctx, containerd.WithoutRefreshedMetadata)
+	if err != nil {
+		return runtime.Errorf("failed to get container info: %v", err)
+	}
+
+	labels := make(map[string]string)
+	for k, v := range info.Labels {
+		if k != containerdGenerationLabel && k != containerdUIDLabel &&
+			k != containerdPlatformLabel {
+			labels[k] = v
+		}
+	}
+	ctr.labels = labels
+	ctr.createdAt = info.CreatedAt
+	ctr.updatedAt = info.UpdatedAt
+	return nil
+}
+
 // getContainer looks up the container by domain, id, and generation. It returns not-found
 // error if the container doesn't exist.
 //
@@ -237,6 +263,10 @@ func getContainer(ctx context.Context,
 	}
 
 	ctr := newContainer(ctrdRun, ctrdCtr, domain, id, generation, uid, spec)
+	err = ctr.updateInfo(ctx)
+	if err != nil {
+		return nil, err
+	}
 
 	return ctr, nil
 }
@@ -377,13 +407,15 @@ func (ctr *container) Name() string {
 }
 
 func (ctr *container) CreatedAt() time.Time {
-	// TODO: Container.CreatedAt not yet supported by containerd?
-	return time.Now()
+	return ctr.createdAt
 }
 
 func (ctr *container) UpdatedAt() time.Time {
-	// TODO: Container.updatedAt not yet supported by containerd?
-	return time.Now()
+	return ctr.updatedAt
+}
+
+func (ctr *container) Labels() map[string]string {
+	return ctr.labels
 }
 
 func (ctr *container) Domain() [16]byte {
@@ -407,7 +439,28 @@ func (ctr *container) Image(ctx context.Context) (runtime.Image, error) {
 	if err != nil {
 		return nil, err
 	}
-	return newImage(ctx, ctr.ctrdRuntime, img)
+	labels, err := ctr.ctrdContainer.Labels(ctx)
+	if err != nil {
+		return nil, runtime.Errorf("failed to get container labels: %v", err)
+	}
+
+	platform, ok := labels[containerdPlatformLabel]
+	if !ok {
+		// containers created without the label can only be matched to the local platforms
+		runImg, err := newImage(ctx, ctr.ctrdRuntime, img, platforms.DefaultString())
+		if err != nil && errors.Is(err, ctrderr.ErrNotFound) {
+			return localPlatformImage(ctx, ctr.ctrdRuntime, img.Metadata())
+		}
+		return runImg, err
+	}
+
+	p, err := parsePlatform(platform)
+	if err != nil {
+		return nil, err
+	}
+	ctrdImg := containerd.NewImageWithPlatform(ctr.ctrdRuntime.client, img.Metadata(),
+		platforms.Only(p))
+	return newImage(ctx, ctr.ctrdRuntime, ctrdImg, platforms.Format(p))
 }
 
 func (ctr *container) Snapshots(ctx context.Context) ([]runtime.Snapshot, error) {
@@ -415,6 +468,10 @@ func (ctr *container) Snapshots(ctx context.Context) ([]runtime.Snapshot, error)
 	return getSnapshots(ctx, ctr.ctrdRuntime)
 }
 
+func (ctr *container) ActiveSnapshot(ctx context.Context) (runtime.Snapshot, error) {
+	return getActiveSnapshot(ctx, ctr.ctrdRuntime, ctr.domain, ctr.id)
+}
+
 func (ctr *container) SetRootFS(ctx context.Context, snapName string) error {
 	return createActiveSnapshot(ctx, ctr.ctrdRuntime, ctr.domain, ctr.id, snapName)
 }
@@ -470,8 +527,12 @@ func (ctr *container) Create(ctx context.Context, img runtime.Image, options map
 
 	// create container
 	labels := map[string]string{}
+	for k, v := range ctr.labels {
+		labels[k] = v
+	}
 	labels[containerdGenerationLabel] = gen
 	labels[containerdUIDLabel] = strconv.FormatUint(uint64(ctr.uid), 10)
+	labels[containerdPlatformLabel] = img.Platform()
 
 	opts := updateSpecOpts(&spec, options)
 	ctrdCtr, err = ctrdRun.client.NewContainer(
@@ -486,7 +547,7 @@ func (ctr *container) Create(ctx context.Context, img runtime.Image, options map
 	}
 
 	ctr.ctrdContainer = ctrdCtr
-	return nil
+	return ctr.updateInfo(ctx)
 }
 
 func (ctr *container) Delete(ctx context.Context) error {
@@ -564,7 +625,7 @@ func (ctr *container) Commit(ctx context.Context, gen [16]byte) error {
 		return err
 	}
 
-	return nil
+	return ctr.updateInfo(ctx)
 }
 
 func (ctr *container) Snapshot(ctx context.Context) (runtime.Snapshot, error) {
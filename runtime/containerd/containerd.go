@@ -5,11 +5,17 @@ package containerd
 
 import (
 	"context"
+	"errors"
+	"io"
 	"os"
 
 	"github.com/containerd/containerd"
+	ctrderr "github.com/containerd/containerd/errdefs"
 	"github.com/containerd/containerd/images"
 	"github.com/containerd/containerd/namespaces"
+	"github.com/containerd/containerd/platforms"
+
+	digest "github.com/opencontainers/go-digest"
 
 	"github.com/czankel/cne/config"
 	"github.com/czankel/cne/runtime"
@@ -18,10 +24,15 @@ import (
 const containerdGenerationLabel = "CNE-GEN"
 const containerdUIDLabel = "CNE-UID"
 
+// containerdPlatformLabel is the platform of the image the container was created from
+const containerdPlatformLabel = "CNE-PLATFORM"
+
 // containerdRuntime provides the runtime implementation for the containerd daemon
 // For more information about containerd, see: https://github.com/containerd/containerd
 type containerdRuntime struct {
-	client *containerd.Client
+	client      *containerd.Client
+	registries  map[string]*config.Registry
+	credentials runtime.Credentials
 }
 
 type containerdEngine struct {
@@ -64,6 +75,14 @@ func (ctrdRun *containerdRuntime) Close() {
 	ctrdRun.client.Close()
 }
 
+func (ctrdRun *containerdRuntime) SetRegistries(regs map[string]*config.Registry) {
+	ctrdRun.registries = regs
+}
+
+func (ctrdRun *containerdRuntime) SetCredentials(creds runtime.Credentials) {
+	ctrdRun.credentials = creds
+}
+
 func (ctrdRun *containerdRuntime) Images(ctx context.Context) ([]runtime.Image, error) {
 
 	ctrdImgs, err := ctrdRun.client.ListImages(ctx)
@@ -73,7 +92,11 @@ func (ctrdRun *containerdRuntime) Images(ctx context.Context) ([]runtime.Image,
 
 	runImgs := make([]runtime.Image, len(ctrdImgs))
 	for i, ctrdImg := range ctrdImgs {
-		runImg, err := newImage(ctx, ctrdRun, ctrdImg)
+		runImg, err := newImage(ctx, ctrdRun, ctrdImg, platforms.DefaultString())
+		if err != nil && errors.Is(err, ctrderr.ErrNotFound) {
+			// image pulled for a different platform than the platform of the host
+			runImg, err = localPlatformImage(ctx, ctrdRun, ctrdImg.Metadata())
+		}
 		if err != nil {
 			return nil, err
 		}
@@ -83,13 +106,29 @@ func (ctrdRun *containerdRuntime) Images(ctx context.Context) ([]runtime.Image,
 	return runImgs, nil
 }
 
-func (ctrdRun *containerdRuntime) GetImage(ctx context.Context, name string) (runtime.Image, error) {
-	return getImage(ctx, *ctrdRun, name)
+func (ctrdRun *containerdRuntime) GetImage(ctx context.Context,
+	name, platform string) (runtime.Image, error) {
+	return getImage(ctx, *ctrdRun, name, platform)
 }
 
-func (ctrdRun *containerdRuntime) PullImage(ctx context.Context, name string,
+func (ctrdRun *containerdRuntime) PullImage(ctx context.Context, name, platform string,
 	progress chan<- []runtime.ProgressStatus) (runtime.Image, error) {
-	return pullImage(ctx, ctrdRun, name, progress)
+	return pullImage(ctx, ctrdRun, name, platform, progress)
+}
+
+func (ctrdRun *containerdRuntime) ImportImage(ctx context.Context,
+	r io.Reader, platform string) ([]runtime.Image, error) {
+	return importImage(ctx, ctrdRun, r, platform)
+}
+
+func (ctrdRun *containerdRuntime) ExportImage(ctx context.Context,
+	name, platform string, w io.Writer) error {
+	return exportImage(ctx, ctrdRun, name, platform, w)
+}
+
+func (ctrdRun *containerdRuntime) ResolveImage(ctx context.Context,
+	name string) (digest.Digest, error) {
+	return resolveImage(ctx, ctrdRun, name)
 }
 
 func (ctrdRun *containerdRuntime) DeleteImage(ctx context.Context, name string) error {
@@ -119,6 +158,24 @@ func (ctrdRun *containerdRuntime) DeleteSnapshot(ctx context.Context,
 	return deleteSnapshot(ctx, ctrdRun, name)
 }
 
+func (ctrdRun *containerdRuntime) SetSnapshotLabel(ctx context.Context,
+	name, key, value string) error {
+
+	return setSnapshotLabel(ctx, ctrdRun, name, key, value)
+}
+
+func (ctrdRun *containerdRuntime) ExportSnapshots(ctx context.Context,
+	names []string, w io.Writer) error {
+
+	return exportSnapshots(ctx, ctrdRun, names, w)
+}
+
+func (ctrdRun *containerdRuntime) ImportSnapshots(ctx context.Context,
+	r io.Reader) ([]string, error) {
+
+	return importSnapshots(ctx, ctrdRun, r)
+}
+
 func (ctrdRun *containerdRuntime) Containers(ctx context.Context,
 	filters ...interface{}) ([]runtime.Container, error) {
 	return getContainers(ctx, ctrdRun, filters...)
@@ -130,7 +187,8 @@ func (ctrdRun *containerdRuntime) GetContainer(ctx context.Context,
 }
 
 func (ctrdRun *containerdRuntime) NewContainer(ctx context.Context,
-	domain, id, generation [16]byte, uid uint32) (runtime.Container, error) {
+	domain, id, generation [16]byte, uid uint32,
+	labels map[string]string) (runtime.Container, error) {
 
 	// start with a base container
 	spec, err := runtime.DefaultSpec(ctx)
@@ -138,7 +196,9 @@ func (ctrdRun *containerdRuntime) NewContainer(ctx context.Context,
 		return nil, err
 	}
 
-	return newContainer(ctrdRun, nil, domain, id, generation, uid, &spec), nil
+	ctr := newContainer(ctrdRun, nil, domain, id, generation, uid, &spec)
+	ctr.labels = labels
+	return ctr, nil
 }
 
 func (ctrdRun *containerdRuntime) DeleteContainer(ctx context.Context,
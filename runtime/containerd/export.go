@@ -0,0 +1,272 @@
+//go:build linux
+
+package containerd
+
+import (
+	"archive/tar"
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/containerd/containerd"
+	"github.com/containerd/containerd/content"
+	ctrderr "github.com/containerd/containerd/errdefs"
+	"github.com/containerd/containerd/snapshots"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+
+	"github.com/czankel/cne/errdefs"
+	"github.com/czankel/cne/runtime"
+)
+
+// snapshotIndexName is the name of the file in the archive that describes the snapshots.
+const snapshotIndexName = "index.json"
+
+// snapshotRecord describes an exported snapshot and the diff to its parent snapshot.
+type snapshotRecord struct {
+	Name   string             `json:"name"`
+	Parent string             `json:"parent"`
+	Labels map[string]string  `json:"labels,omitempty"`
+	Diff   ocispec.Descriptor `json:"diff"`
+}
+
+// blobName returns the name of the diff in the archive
+func blobName(desc ocispec.Descriptor) string {
+	return "blobs/" + desc.Digest.Algorithm().String() + "/" + desc.Digest.Encoded()
+}
+
+// diffSnapshot creates the diff of the committed snapshot to its parent snapshot
+func diffSnapshot(ctx context.Context, ctrdRun *containerdRuntime,
+	info snapshots.Info) (ocispec.Descriptor, error) {
+
+	snapSvc := ctrdRun.client.SnapshotService(containerd.DefaultSnapshotter)
+	diffSvc := ctrdRun.client.DiffService()
+
+	parentMnts, err := snapSvc.View(ctx, info.Parent+"-export", info.Parent)
+	if err != nil {
+		return ocispec.Descriptor{},
+			runtime.Errorf("creating snapshot '%v' failed: %v", info.Parent, err)
+	}
+	defer snapSvc.Remove(ctx, info.Parent+"-export")
+
+	snapMnts, err := snapSvc.View(ctx, info.Name+"-export", info.Name)
+	if err != nil {
+		return ocispec.Descriptor{},
+			runtime.Errorf("creating snapshot '%v' failed: %v", info.Name, err)
+	}
+	defer snapSvc.Remove(ctx, info.Name+"-export")
+
+	desc, err := diffSvc.Compare(ctx, parentMnts, snapMnts)
+	if err != nil {
+		return ocispec.Descriptor{},
+			runtime.Errorf("failed to create diff between snapshots: %v", err)
+	}
+	return desc, nil
+}
+
+// exportSnapshots writes the snapshot index and the diffs of the snapshots as a tar archive.
+func exportSnapshots(ctx context.Context, ctrdRun *containerdRuntime,
+	names []string, w io.Writer) error {
+
+	ctx, done, err := ctrdRun.client.WithLease(ctx)
+	if err != nil {
+		return runtime.Errorf("failed to create lease: %v", err)
+	}
+	defer done(ctx)
+
+	snapSvc := ctrdRun.client.SnapshotService(containerd.DefaultSnapshotter)
+
+	records := make([]snapshotRecord, len(names))
+	for i, name := range names {
+
+		info, err := snapSvc.Stat(ctx, name)
+		if err != nil && ctrderr.IsNotFound(err) {
+			return errdefs.NotFound("snapshot", name)
+		}
+		if err != nil {
+			return runtime.Errorf("failed to get snapshot: %v", err)
+		}
+		if info.Kind != snapshots.KindCommitted || info.Parent == "" {
+			return errdefs.InvalidArgument("snapshot '%s' cannot be exported", name)
+		}
+
+		desc, err := diffSnapshot(ctx, ctrdRun, info)
+		if err != nil {
+			return err
+		}
+
+		// containerd labels are local to the system
+		labels := map[string]string{}
+		for k, v := range info.Labels {
+			if !strings.HasPrefix(k, "containerd.io/") {
+				labels[k] = v
+			}
+		}
+
+		records[i] = snapshotRecord{
+			Name:   info.Name,
+			Parent: info.Parent,
+			Labels: labels,
+			Diff:   desc,
+		}
+	}
+
+	index, err := json.Marshal(records)
+	if err != nil {
+		return runtime.Errorf("failed to encode snapshot index: %v", err)
+	}
+
+	tw := tar.NewWriter(w)
+	err = tw.WriteHeader(&tar.Header{
+		Name:    snapshotIndexName,
+		Mode:    0644,
+		Size:    int64(len(index)),
+		ModTime: time.Now(),
+	})
+	if err == nil {
+		_, err = tw.Write(index)
+	}
+	if err != nil {
+		return runtime.Errorf("failed to write archive: %v", err)
+	}
+
+	cs := ctrdRun.client.ContentStore()
+	for _, r := range records {
+
+		ra, err := cs.ReaderAt(ctx, r.Diff)
+		if err != nil {
+			return runtime.Errorf("failed to read diff of snapshot '%s': %v", r.Name, err)
+		}
+
+		err = tw.WriteHeader(&tar.Header{
+			Name:    blobName(r.Diff),
+			Mode:    0644,
+			Size:    r.Diff.Size,
+			ModTime: time.Now(),
+		})
+		if err == nil {
+			_, err = io.Copy(tw, content.NewReader(ra))
+		}
+		ra.Close()
+		if err != nil {
+			return runtime.Errorf("failed to write archive: %v", err)
+		}
+	}
+
+	err = tw.Close()
+	if err != nil {
+		return runtime.Errorf("failed to write archive: %v", err)
+	}
+	return nil
+}
+
+// importSnapshot applies the diff of the snapshot to its parent and commits the snapshot.
+func importSnapshot(ctx context.Context, ctrdRun *containerdRuntime, r snapshotRecord) error {
+
+	snapSvc := ctrdRun.client.SnapshotService(containerd.DefaultSnapshotter)
+	diffSvc := ctrdRun.client.DiffService()
+
+	_, err := snapSvc.Stat(ctx, r.Parent)
+	if err != nil && ctrderr.IsNotFound(err) {
+		return errdefs.NotFound("snapshot", r.Parent)
+	}
+	if err != nil {
+		return runtime.Errorf("failed to get snapshot: %v", err)
+	}
+
+	key := r.Name + "-import"
+	mnts, err := snapSvc.Prepare(ctx, key, r.Parent)
+	if err != nil {
+		return runtime.Errorf("failed to create temporary snapshot: %v", err)
+	}
+
+	_, err = diffSvc.Apply(ctx, r.Diff, mnts)
+	if err != nil {
+		snapSvc.Remove(ctx, key)
+		return runtime.Errorf("failed to apply snapshot: %v", err)
+	}
+
+	labels := map[string]string{}
+	for k, v := range r.Labels {
+		labels[k] = v
+	}
+	labels["containerd.io/gc.root"] = time.Now().UTC().Format(time.RFC3339)
+
+	err = snapSvc.Commit(ctx, r.Name, key, snapshots.WithLabels(labels))
+	if err != nil {
+		snapSvc.Remove(ctx, key)
+		return runtime.Errorf("failed to commit snapshot: %v", err)
+	}
+	return nil
+}
+
+// importSnapshots reads an archive written by exportSnapshots and creates all snapshots that
+// don't already exist. It returns the names of the created snapshots.
+func importSnapshots(ctx context.Context, ctrdRun *containerdRuntime,
+	rd io.Reader) ([]string, error) {
+
+	ctx, done, err := ctrdRun.client.WithLease(ctx)
+	if err != nil {
+		return nil, runtime.Errorf("failed to create lease: %v", err)
+	}
+	defer done(ctx)
+
+	tr := tar.NewReader(rd)
+	hdr, err := tr.Next()
+	if err != nil || hdr.Name != snapshotIndexName {
+		return nil, errdefs.InvalidArgument("invalid cache archive")
+	}
+
+	var records []snapshotRecord
+	err = json.NewDecoder(tr).Decode(&records)
+	if err != nil {
+		return nil, errdefs.InvalidArgument("invalid cache archive: %v", err)
+	}
+
+	descs := make(map[string]ocispec.Descriptor, len(records))
+	for _, r := range records {
+		descs[blobName(r.Diff)] = r.Diff
+	}
+
+	cs := ctrdRun.client.ContentStore()
+	for {
+		hdr, err = tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errdefs.InvalidArgument("invalid cache archive: %v", err)
+		}
+		desc, ok := descs[hdr.Name]
+		if !ok {
+			return nil, errdefs.InvalidArgument("invalid cache archive: unknown file '%s'",
+				hdr.Name)
+		}
+		err = content.WriteBlob(ctx, cs, "cne-import-"+desc.Digest.String(), tr, desc)
+		if err != nil && !ctrderr.IsAlreadyExists(err) {
+			return nil, runtime.Errorf("failed to import diff: %v", err)
+		}
+	}
+
+	snapSvc := ctrdRun.client.SnapshotService(containerd.DefaultSnapshotter)
+	var names []string
+	for _, r := range records {
+
+		_, err := snapSvc.Stat(ctx, r.Name)
+		if err == nil {
+			continue
+		}
+		if !ctrderr.IsNotFound(err) {
+			return names, runtime.Errorf("failed to get snapshot: %v", err)
+		}
+
+		err = importSnapshot(ctx, ctrdRun, r)
+		if err != nil {
+			return names, err
+		}
+		names = append(names, r.Name)
+	}
+
+	return names, nil
+}
@@ -4,8 +4,14 @@ package containerd
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
 	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
 	"os/signal"
 	"strings"
 	"sync"
@@ -15,16 +21,20 @@ import (
 	"github.com/containerd/containerd/content"
 	ctrderr "github.com/containerd/containerd/errdefs"
 	"github.com/containerd/containerd/images"
+	"github.com/containerd/containerd/images/archive"
 	"github.com/containerd/containerd/labels"
 	"github.com/containerd/containerd/mount"
+	"github.com/containerd/containerd/platforms"
 	"github.com/containerd/containerd/reference"
 	"github.com/containerd/containerd/remotes"
+	"github.com/containerd/containerd/remotes/docker"
 	"github.com/containerd/containerd/snapshots"
 
 	digest "github.com/opencontainers/go-digest"
 	"github.com/opencontainers/image-spec/identity"
 	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
 
+	"github.com/czankel/cne/config"
 	"github.com/czankel/cne/errdefs"
 	"github.com/czankel/cne/runtime"
 )
@@ -142,10 +152,11 @@ type image struct {
 	ctrdImage   containerd.Image
 	digest      digest.Digest
 	size        int64
+	platform    string
 }
 
 func newImage(ctx context.Context,
-	ctrdRun *containerdRuntime, ctrdImg containerd.Image) (*image, error) {
+	ctrdRun *containerdRuntime, ctrdImg containerd.Image, platform string) (*image, error) {
 
 	imgConf, err := ctrdImg.Config(ctx)
 	if err != nil {
@@ -157,26 +168,80 @@ func newImage(ctx context.Context,
 		ctrdImage:   ctrdImg,
 		digest:      imgConf.Digest,
 		size:        imgConf.Size,
+		platform:    platform,
 	}, nil
 }
 
-func getImage(ctx context.Context, ctrdRun containerdRuntime, name string) (runtime.Image, error) {
-	ctrdImg, err := ctrdRun.client.GetImage(ctx, name)
+// parsePlatform returns the normalized platform or the platform of the host if the platform
+// is empty.
+func parsePlatform(platform string) (ocispec.Platform, error) {
+
+	if platform == "" {
+		return platforms.DefaultSpec(), nil
+	}
+	p, err := platforms.Parse(platform)
+	if err != nil {
+		return ocispec.Platform{}, errdefs.InvalidArgument("invalid platform '%s'", platform)
+	}
+	return platforms.Normalize(p), nil
+}
+
+// localPlatformImage returns the image for the first platform of a multi-platform image that
+// is available locally, for images that weren't pulled for the platform of the host.
+func localPlatformImage(ctx context.Context,
+	ctrdRun *containerdRuntime, img images.Image) (*image, error) {
+
+	specs, err := images.Platforms(ctx, ctrdRun.client.ContentStore(), img.Target)
+	if err != nil {
+		return nil, runtime.Errorf("failed to get platforms of image '%s': %v", img.Name, err)
+	}
+	for _, p := range specs {
+		ctrdImg := containerd.NewImageWithPlatform(ctrdRun.client, img, platforms.Only(p))
+		runImg, err := newImage(ctx, ctrdRun, ctrdImg, platforms.Format(p))
+		if err == nil {
+			return runImg, nil
+		}
+	}
+	return nil, errdefs.NotFound("image", img.Name)
+}
+
+func getImage(ctx context.Context,
+	ctrdRun containerdRuntime, name, platform string) (runtime.Image, error) {
+
+	p, err := parsePlatform(platform)
+	if err != nil {
+		return nil, err
+	}
+
+	imgSvc := ctrdRun.client.ImageService()
+	img, err := imgSvc.Get(ctx, name)
 	if errors.Is(err, ctrderr.ErrNotFound) {
 		return nil, errdefs.NotFound("image", name)
 	} else if err != nil {
 		return nil, err
 	}
 
-	return newImage(ctx, &ctrdRun, ctrdImg)
+	ctrdImg := containerd.NewImageWithPlatform(ctrdRun.client, img, platforms.Only(p))
+	runImg, err := newImage(ctx, &ctrdRun, ctrdImg, platforms.Format(p))
+	if errors.Is(err, ctrderr.ErrNotFound) {
+		return nil, errdefs.NotFound("image", name+" for platform "+platforms.Format(p))
+	} else if err != nil {
+		return nil, err
+	}
+	return runImg, nil
 }
 
 // TODO: ContainerD is not really stable when interrupting an image pull (e.g. using CTRL-C)
 // TODO: Snapshots can stay in extracting stage and never complete.
 
-func pullImage(ctx context.Context, ctrdRun *containerdRuntime, name string,
+func pullImage(ctx context.Context, ctrdRun *containerdRuntime, name, platform string,
 	progress chan<- []runtime.ProgressStatus) (runtime.Image, error) {
 
+	p, err := parsePlatform(platform)
+	if err != nil {
+		return nil, err
+	}
+
 	var mutex sync.Mutex
 	descs := []ocispec.Descriptor{}
 
@@ -214,7 +279,9 @@ func pullImage(ctx context.Context, ctrdRun *containerdRuntime, name string,
 	// ignore signals while pulling - see comment above
 	signal.Ignore()
 
-	ctrdImg, err := ctrdRun.client.Pull(ctx, name, containerd.WithImageHandler(h))
+	ctrdImg, err := ctrdRun.client.Pull(ctx, name,
+		containerd.WithResolver(resolver(ctrdRun)), containerd.WithImageHandler(h),
+		containerd.WithPlatformMatcher(platforms.Only(p)))
 
 	signal.Reset()
 
@@ -224,7 +291,214 @@ func pullImage(ctx context.Context, ctrdRun *containerdRuntime, name string,
 		return nil, runtime.Errorf("pull image '%s' failed: %v", name, err)
 	}
 
-	return newImage(ctx, ctrdRun, ctrdImg)
+	return newImage(ctx, ctrdRun, ctrdImg, platforms.Format(p))
+}
+
+// registryClient returns the HTTP client for the registry using the CA bundle of the registry
+// and skipping the TLS verification for insecure registries.
+func registryClient(reg *config.Registry) (*http.Client, error) {
+
+	tlsConfig := &tls.Config{InsecureSkipVerify: reg.Insecure}
+	if reg.CAFile != "" {
+		pem, err := os.ReadFile(reg.CAFile)
+		if err != nil {
+			return nil, runtime.Errorf("failed to read CA bundle '%s': %v", reg.CAFile, err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, runtime.Errorf("no certificates found in '%s'", reg.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	return &http.Client{Transport: transport}, nil
+}
+
+// endpointHosts returns the registry hosts for the endpoint in the format [scheme://]host[/path].
+// Insecure endpoints without a scheme fall back to plain HTTP.
+func endpointHosts(endpoint string, reg *config.Registry, client *http.Client,
+	authorizer docker.Authorizer, caps docker.HostCapabilities) ([]docker.RegistryHost, error) {
+
+	var schemes []string
+	if !strings.Contains(endpoint, "://") {
+		endpoint = "https://" + endpoint
+		schemes = append(schemes, "https")
+		if reg.Insecure {
+			schemes = append(schemes, "http")
+		}
+	}
+
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Host == "" {
+		return nil, runtime.Errorf("invalid registry endpoint '%s'", endpoint)
+	}
+	if schemes == nil {
+		schemes = []string{u.Scheme}
+	}
+	path := strings.TrimSuffix(u.Path, "/")
+	if !strings.HasSuffix(path, "/v2") {
+		path = path + "/v2"
+	}
+
+	var hosts []docker.RegistryHost
+	for _, scheme := range schemes {
+		hosts = append(hosts, docker.RegistryHost{
+			Client:       client,
+			Authorizer:   authorizer,
+			Host:         u.Host,
+			Scheme:       scheme,
+			Path:         path,
+			Capabilities: caps,
+		})
+	}
+	return hosts, nil
+}
+
+// registryHosts returns the hosts for the registry domain. The mirrors of a configured registry
+// are tried before the registry itself, which is used as a fallback.
+func registryHosts(ctrdRun *containerdRuntime, authorizer docker.Authorizer) docker.RegistryHosts {
+
+	defaultHosts := docker.ConfigureDefaultRegistries(docker.WithAuthorizer(authorizer),
+		docker.WithPlainHTTP(docker.MatchLocalhost))
+
+	return func(domain string) ([]docker.RegistryHost, error) {
+
+		var reg *config.Registry
+		for _, r := range ctrdRun.registries {
+			if r.Domain == domain {
+				reg = r
+				break
+			}
+		}
+		if reg == nil || len(reg.Mirrors) == 0 && !reg.Insecure && reg.CAFile == "" {
+			return defaultHosts(domain)
+		}
+
+		client, err := registryClient(reg)
+		if err != nil {
+			return nil, err
+		}
+
+		var hosts []docker.RegistryHost
+		for _, mirror := range reg.Mirrors {
+			h, err := endpointHosts(mirror, reg, client, authorizer,
+				docker.HostCapabilityPull|docker.HostCapabilityResolve)
+			if err != nil {
+				return nil, err
+			}
+			hosts = append(hosts, h...)
+		}
+
+		// the default hosts map docker.io to its registry host and localhost to plain HTTP
+		origins, err := defaultHosts(domain)
+		if err != nil {
+			return nil, err
+		}
+		for _, o := range origins {
+			endpoint := o.Host + o.Path
+			if o.Scheme == "http" {
+				endpoint = "http://" + endpoint
+			}
+			h, err := endpointHosts(endpoint, reg, client, authorizer, o.Capabilities)
+			if err != nil {
+				return nil, err
+			}
+			hosts = append(hosts, h...)
+		}
+		return hosts, nil
+	}
+}
+
+// resolver returns the resolver for pulling and resolving images with the configuration and
+// the credentials of the registries.
+func resolver(ctrdRun *containerdRuntime) remotes.Resolver {
+
+	var authOpts []docker.AuthorizerOpt
+	if ctrdRun.credentials != nil {
+		authOpts = append(authOpts, docker.WithAuthCreds(ctrdRun.credentials))
+	}
+	authorizer := docker.NewDockerAuthorizer(authOpts...)
+
+	return docker.NewResolver(docker.ResolverOptions{
+		Hosts: registryHosts(ctrdRun, authorizer),
+	})
+}
+
+// resolveImage resolves the image name in the registry and returns the digest of the target
+// descriptor.
+func resolveImage(ctx context.Context,
+	ctrdRun *containerdRuntime, name string) (digest.Digest, error) {
+
+	_, desc, err := resolver(ctrdRun).Resolve(ctx, name)
+	if err == reference.ErrObjectRequired {
+		return "", runtime.Errorf("invalid image name '%s': %v", name, err)
+	} else if err != nil {
+		return "", runtime.Errorf("resolve image '%s' failed: %v", name, err)
+	}
+	return desc.Digest, nil
+}
+
+// importImage imports the images of the archive for the platform
+func importImage(ctx context.Context,
+	ctrdRun *containerdRuntime, r io.Reader, platform string) ([]runtime.Image, error) {
+
+	p, err := parsePlatform(platform)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, done, err := ctrdRun.client.WithLease(ctx)
+	if err != nil {
+		return nil, runtime.Errorf("failed to create lease: %v", err)
+	}
+	defer done(ctx)
+
+	ctrdImgs, err := ctrdRun.client.Import(ctx, r,
+		containerd.WithImportPlatform(platforms.Only(p)))
+	if err != nil {
+		return nil, runtime.Errorf("import image failed: %v", err)
+	}
+
+	runImgs := make([]runtime.Image, len(ctrdImgs))
+	for i, img := range ctrdImgs {
+		ctrdImg := containerd.NewImageWithPlatform(ctrdRun.client, img, platforms.Only(p))
+		runImg, err := newImage(ctx, ctrdRun, ctrdImg, platforms.Format(p))
+		if err != nil {
+			return nil, runtime.Errorf("import image '%s' failed: %v", img.Name, err)
+		}
+		runImgs[i] = runImg
+	}
+	return runImgs, nil
+}
+
+// exportImage exports the image for the platform
+func exportImage(ctx context.Context,
+	ctrdRun *containerdRuntime, name, platform string, w io.Writer) error {
+
+	p, err := parsePlatform(platform)
+	if err != nil {
+		return err
+	}
+
+	_, err = ctrdRun.client.GetImage(ctx, name)
+	if errors.Is(err, ctrderr.ErrNotFound) {
+		return errdefs.NotFound("image", name)
+	} else if err != nil {
+		return err
+	}
+
+	err = ctrdRun.client.Export(ctx, w,
+		archive.WithImage(ctrdRun.client.ImageService(), name),
+		archive.WithPlatform(platforms.Only(p)))
+	if err != nil {
+		return runtime.Errorf("export image '%s' failed: %v", name, err)
+	}
+	return nil
 }
 
 // Image interface
@@ -241,11 +515,20 @@ func (img *image) Digest() digest.Digest {
 	return img.digest
 }
 
+func (img *image) RepoDigest() digest.Digest {
+	return img.ctrdImage.Target().Digest
+}
+
+func (img *image) Platform() string {
+	return img.platform
+}
+
 func (img *image) CreatedAt() time.Time {
-	/* TODO: Image.Metadata()Is supposed to be available in containerd 1.3.3
-	   return img.ctrdImage.Metadata().CreatedAt
-	*/
-	return time.Now()
+	return img.ctrdImage.Metadata().CreatedAt
+}
+
+func (img *image) UpdatedAt() time.Time {
+	return img.ctrdImage.Metadata().UpdatedAt
 }
 
 func (img *image) Config(ctx context.Context) (*ocispec.ImageConfig, error) {
@@ -0,0 +1,157 @@
+package containerd
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/containerd/containerd/remotes/docker"
+	digest "github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+
+	"github.com/czankel/cne/config"
+)
+
+func TestRegistryHosts(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		registry *config.Registry
+		domain   string
+		hosts    []string
+	}{
+		{
+			name:   "default",
+			domain: "example.com",
+			hosts:  []string{"https://example.com/v2"},
+		},
+		{
+			name:   "docker.io",
+			domain: "docker.io",
+			hosts:  []string{"https://registry-1.docker.io/v2"},
+		},
+		{
+			name:   "localhost",
+			domain: "localhost:5000",
+			hosts:  []string{"http://localhost:5000/v2"},
+		},
+		{
+			name:     "insecure",
+			registry: &config.Registry{Domain: "example.com", Insecure: true},
+			domain:   "example.com",
+			hosts:    []string{"https://example.com/v2", "http://example.com/v2"},
+		},
+		{
+			name: "mirrors",
+			registry: &config.Registry{Domain: "docker.io",
+				Mirrors: []string{"mirror.example.com", "http://proxy.example.com:5000/hub"}},
+			domain: "docker.io",
+			hosts: []string{
+				"https://mirror.example.com/v2",
+				"http://proxy.example.com:5000/hub/v2",
+				"https://registry-1.docker.io/v2",
+			},
+		},
+		{
+			name: "insecure mirror",
+			registry: &config.Registry{Domain: "example.com", Insecure: true,
+				Mirrors: []string{"mirror.example.com/v2"}},
+			domain: "example.com",
+			hosts: []string{
+				"https://mirror.example.com/v2",
+				"http://mirror.example.com/v2",
+				"https://example.com/v2",
+				"http://example.com/v2",
+			},
+		},
+		{
+			name: "localhost with mirror",
+			registry: &config.Registry{Domain: "localhost:5000",
+				Mirrors: []string{"mirror.example.com"}},
+			domain: "localhost:5000",
+			hosts: []string{
+				"https://mirror.example.com/v2",
+				"http://localhost:5000/v2",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrdRun := &containerdRuntime{}
+			if tt.registry != nil {
+				ctrdRun.registries = map[string]*config.Registry{"test": tt.registry}
+			}
+
+			hosts, err := registryHosts(ctrdRun, docker.NewDockerAuthorizer())(tt.domain)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if len(hosts) != len(tt.hosts) {
+				t.Fatalf("Expected %d hosts, got %d: %v", len(tt.hosts), len(hosts), hosts)
+			}
+			for i, h := range hosts {
+				if u := h.Scheme + "://" + h.Host + h.Path; u != tt.hosts[i] {
+					t.Errorf("Expected host %d to be '%s', got '%s'", i, tt.hosts[i], u)
+				}
+			}
+		})
+	}
+}
+
+func TestEndpointHostsInvalid(t *testing.T) {
+
+	_, err := endpointHosts("https://", &config.Registry{}, nil, nil, docker.HostCapabilityPull)
+	if err == nil {
+		t.Errorf("Endpoint without host should have failed")
+	}
+}
+
+// testRegistry returns a registry that serves the manifest of test/image:latest with the digest.
+func testRegistry(dgst digest.Digest) *httptest.Server {
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/v2/":
+			w.WriteHeader(http.StatusOK)
+		case r.Method == http.MethodHead && r.URL.Path == "/v2/test/image/manifests/latest":
+			w.Header().Set("Content-Type", ocispec.MediaTypeImageManifest)
+			w.Header().Set("Content-Length", "100")
+			w.Header().Set("Docker-Content-Digest", dgst.String())
+			w.WriteHeader(http.StatusOK)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func TestResolveImage(t *testing.T) {
+
+	dgst := digest.FromString("manifest")
+	srv := testRegistry(dgst)
+	defer srv.Close()
+	host := strings.TrimPrefix(srv.URL, "http://")
+
+	ctrdRun := &containerdRuntime{}
+	ctrdRun.SetCredentials(func(host string) (string, string, error) { return "", "", nil })
+
+	res, err := ctrdRun.ResolveImage(context.Background(), host+"/test/image:latest")
+	if err != nil {
+		t.Fatalf("Failed to resolve image: %v", err)
+	}
+	if res != dgst {
+		t.Errorf("Expected digest %s, got %s", dgst, res)
+	}
+
+	_, err = ctrdRun.ResolveImage(context.Background(), host+"/test/missing:latest")
+	if err == nil {
+		t.Errorf("Resolving a missing image should have failed")
+	}
+
+	_, err = ctrdRun.ResolveImage(context.Background(), "test/image")
+	if err == nil {
+		t.Errorf("Resolving an image without a domain should have failed")
+	}
+}
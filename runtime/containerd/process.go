@@ -56,3 +56,12 @@ func (proc *process) Signal(ctx context.Context, sig os.Signal) error {
 	}
 	return nil
 }
+
+func (proc *process) Resize(ctx context.Context, width, height uint32) error {
+
+	err := proc.ctrdProc.Resize(ctx, width, height)
+	if err != nil {
+		return runtime.Errorf("resize failed: %v", err)
+	}
+	return nil
+}
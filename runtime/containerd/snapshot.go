@@ -288,6 +288,10 @@ func createActiveSnapshot(ctx context.Context, ctrdRun *containerdRuntime,
 		if err != nil {
 			return err
 		}
+		// keep the layer snapshots of the build cache and opaque layers
+		if runtime.IsLayerSnapshot(snap) {
+			break
+		}
 		err = deleteSnapshot(ctx, ctrdRun, name)
 		if err != nil && errors.Is(err, errdefs.ErrNotFound) {
 			break
@@ -356,6 +360,25 @@ func deleteSnapshot(ctx context.Context, ctrdRun *containerdRuntime, snapName st
 	return nil
 }
 
+// setSnapshotLabel sets or, for an empty value, removes the label of the snapshot.
+func setSnapshotLabel(ctx context.Context, ctrdRun *containerdRuntime,
+	snapName, key, value string) error {
+
+	snapSvc := ctrdRun.client.SnapshotService(containerd.DefaultSnapshotter)
+	info := snapshots.Info{
+		Name:   snapName,
+		Labels: map[string]string{key: value},
+	}
+	_, err := snapSvc.Update(ctx, info, "labels."+key)
+	if err != nil && ctrderr.IsNotFound(err) {
+		return errdefs.NotFound("snapshot", snapName)
+	}
+	if err != nil {
+		return runtime.Errorf("failed to update snapshot labels: %v", err)
+	}
+	return nil
+}
+
 func deleteActiveSnapshot(ctx context.Context, ctrdRun *containerdRuntime, domain, id [16]byte) error {
 	activeSnapName := activeSnapshotName(domain, id)
 	err := deleteSnapshot(ctx, ctrdRun, activeSnapName)
@@ -438,3 +461,7 @@ func (snap *snapshot) Size() int64 {
 func (snap *snapshot) Inodes() int64 {
 	return snap.inodes
 }
+
+func (snap *snapshot) Labels() map[string]string {
+	return snap.info.Labels
+}
@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"strings"
 	"time"
 
 	digest "github.com/opencontainers/go-digest"
@@ -27,11 +28,23 @@ type Runtime interface {
 	// Close closes the runtime and any open descriptors
 	Close()
 
+	// SetRegistries sets the configuration of the registries with the mirrors, TLS settings,
+	// and CA bundles used when pulling or resolving images from the registry domains.
+	SetRegistries(regs map[string]*config.Registry)
+
+	// SetCredentials sets the function for looking up the credentials of registry hosts when
+	// pulling or resolving images.
+	SetCredentials(creds Credentials)
+
 	// Images returns a list of images that are registered in the runtime
 	Images(ctx context.Context) ([]Image, error)
 
 	// GetImage returns an already pulled image or ErrNotFound if the image wasn't found.
-	GetImage(ctx context.Context, name string) (Image, error)
+	//
+	// The platform in the format os/arch[/variant] selects the image of a multi-platform
+	// image. An empty platform selects the platform of the host. GetImage returns ErrNotFound
+	// if the image wasn't pulled for the platform.
+	GetImage(ctx context.Context, name, platform string) (Image, error)
 
 	// PullImage pulls an image into a local registry and returns an image instance.
 	//
@@ -41,11 +54,27 @@ type Runtime interface {
 	//
 	// Note that the status sent may exclude status information for entries that haven't
 	// changed.
-	PullImage(ctx context.Context, name string, progress chan<- []ProgressStatus) (Image, error)
+	//
+	// The platform selects the image of a multi-platform image as described for GetImage.
+	PullImage(ctx context.Context, name, platform string,
+		progress chan<- []ProgressStatus) (Image, error)
 
 	// DeleteImage deletes the specified image from the registry.
 	DeleteImage(ctx context.Context, name string) error
 
+	// ImportImage imports the images for the platform from a tar archive in the OCI or Docker
+	// image format and returns the imported images. The images are not unpacked. An empty
+	// platform selects the platform of the host.
+	ImportImage(ctx context.Context, r io.Reader, platform string) ([]Image, error)
+
+	// ExportImage writes the image for the platform as a tar archive in the OCI image format
+	// that can be imported with ImportImage. An empty platform selects the platform of the host.
+	ExportImage(ctx context.Context, name, platform string, w io.Writer) error
+
+	// ResolveImage returns the digest of the manifest or index of the image in the registry
+	// without pulling the image.
+	ResolveImage(ctx context.Context, name string) (digest.Digest, error)
+
 	// Snapshots returns all snapshots.
 	Snapshots(ctx context.Context) ([]Snapshot, error)
 
@@ -55,6 +84,18 @@ type Runtime interface {
 	// DeleteSnapshot deletes the snapshot
 	DeleteSnapshot(ctx context.Context, name string) error
 
+	// SetSnapshotLabel sets the label of the snapshot or removes it for an empty value.
+	SetSnapshotLabel(ctx context.Context, name, key, value string) error
+
+	// ExportSnapshots writes the committed snapshots with their labels and the diffs to their
+	// parent snapshots as a tar archive. Parents must be listed before their children.
+	ExportSnapshots(ctx context.Context, names []string, w io.Writer) error
+
+	// ImportSnapshots imports the snapshots from an archive written by ExportSnapshots and
+	// returns the names of the imported snapshots. Existing snapshots are skipped, and the
+	// parent of each snapshot must exist or be part of the archive.
+	ImportSnapshots(ctx context.Context, r io.Reader) ([]string, error)
+
 	// Containers returns all containers in the specified domain.
 	// FIXME: describe filters...
 	Containers(ctx context.Context, filters ...interface{}) ([]Container, error)
@@ -65,9 +106,10 @@ type Runtime interface {
 	// The container can be used to execute commands with Exec.
 	GetContainer(ctx context.Context, domain, id, generation [16]byte) (Container, error)
 
-	// NewContainer defines a new Container without creating it.
-	NewContainer(ctx context.Context,
-		domain, id, generation [16]byte, uid uint32) (Container, error)
+	// NewContainer defines a new Container without creating it. The labels are stored with
+	// the container when it is created.
+	NewContainer(ctx context.Context, domain, id, generation [16]byte, uid uint32,
+		labels map[string]string) (Container, error)
 
 	// DeleteContainer deletes the specified container. It returns ErrNotFound if the container
 	// doesn't exist.
@@ -78,6 +120,10 @@ type Runtime interface {
 	PurgeContainer(ctx context.Context, domain, id, generation [16]byte) error
 }
 
+// Credentials returns the username and secret for the registry host or empty strings for
+// anonymous access.
+type Credentials func(host string) (string, string, error)
+
 // Image describes an image that consists of a file system and configuration options.
 type Image interface {
 
@@ -90,9 +136,18 @@ type Image interface {
 	// Digest returns the digest of the image.
 	Digest() digest.Digest
 
+	// RepoDigest returns the digest of the manifest or index the image was pulled with.
+	RepoDigest() digest.Digest
+
+	// Platform returns the platform of the image in the format os/arch[/variant].
+	Platform() string
+
 	// CreatedAt returns the data the image was created.
 	CreatedAt() time.Time
 
+	// UpdatedAt returns the date the image was last pulled or imported.
+	UpdatedAt() time.Time
+
 	// Unpack unpacks the image.
 	Unpack(ctx context.Context, progress chan<- []ProgressStatus) error
 
@@ -151,12 +206,19 @@ type Container interface {
 	// Return the User ID
 	UID() uint32
 
+	// Labels returns the labels that were provided when the container was created.
+	Labels() map[string]string
+
 	// Image return the image associated to the container
 	Image(ctx context.Context) (Image, error)
 
 	// Snapshots returns all container snapshots.
 	Snapshots(ctx context.Context) ([]Snapshot, error)
 
+	// ActiveSnapshot returns the mutable snapshot of the root filesystem of the container.
+	// It returns ErrNotFound if the container doesn't have an active snapshot.
+	ActiveSnapshot(ctx context.Context) (Snapshot, error)
+
 	// SetRootFS sets the rootfs to the provided snapshot (by name).
 	//
 	// The root filesystem can only be set when the container has not been created.
@@ -230,6 +292,41 @@ type Snapshot interface {
 
 	// Inodex returns the number of additional inodes in the snapshot.
 	Inodes() int64
+
+	// Labels returns the labels of the snapshot.
+	Labels() map[string]string
+}
+
+// Container labels for the project and workspace of the container and the cne version that
+// created the container.
+const (
+	ProjectNameLabel   = "cne.project.name"
+	ProjectPathLabel   = "cne.project.path"
+	WorkspaceNameLabel = "cne.workspace.name"
+	VersionLabel       = "cne.version"
+)
+
+// CacheKeyLabel is the snapshot label for the build cache key of a layer snapshot.
+// Snapshots with a cache key can be reused by later builds.
+const CacheKeyLabel = "cne.cache-key"
+
+// WorkspaceLabel is the snapshot label for the project UUID and workspace name in the format
+// "uuid/name" of the workspace that built or committed the layer snapshot.
+const WorkspaceLabel = "cne.workspace"
+
+// LabelPrefix is the prefix of all CNE labels
+const LabelPrefix = "cne."
+
+// IsLayerSnapshot checks if the snapshot has a CNE label, which is set for the snapshots of
+// workspace layers. Layer snapshots are kept when the root filesystem of a container changes.
+func IsLayerSnapshot(snap Snapshot) bool {
+
+	for k := range snap.Labels() {
+		if strings.HasPrefix(k, LabelPrefix) {
+			return true
+		}
+	}
+	return false
 }
 
 // Process describes a process running inside a container.
@@ -240,6 +337,9 @@ type Process interface {
 
 	// Wait waits asynchronously for the process to exit and sends the exit code to the channel.
 	Wait(ctx context.Context) (<-chan ExitStatus, error)
+
+	// Resize changes the size of the terminal of the process.
+	Resize(ctx context.Context, width, height uint32) error
 }
 
 const (
@@ -248,6 +348,7 @@ const (
 	StatusLoading   = "loading"
 	StatusUnpacking = "unpacking"
 	StatusCached    = "cached"
+	StatusSkipped   = "skipped"
 	StatusRunning   = "running"
 	StatusComplete  = "complete"
 	StatusError     = "error"
@@ -35,11 +35,13 @@ func getAptInstallCommand(aptLayer *project.Layer) (int, *project.Command) {
 func AptLayerInit(layer *project.Layer) error {
 
 	layer.Commands = []project.Command{{
-		aptLayerCmdUpdate, []string{}, []string{"apt", "update"},
+		Name: aptLayerCmdUpdate,
+		Envs: []string{},
+		Args: []string{"apt", "update"},
 	}, {
-		aptLayerCmdUpgrade,
-		[]string{"DEBIAN_FRONTEND=noninteractive"},
-		[]string{
+		Name: aptLayerCmdUpgrade,
+		Envs: []string{"DEBIAN_FRONTEND=noninteractive"},
+		Args: []string{
 			"{{if .Environment.Update == auto || " +
 				".Environment.Update == manual && " +
 				".Parameters.Upgrade in [apt, all]}}",
@@ -63,9 +65,9 @@ func AptInstall(ctx context.Context, ws *project.Workspace, aptLayerIdx int, use
 
 		aptLayer.Commands = append(aptLayer.Commands,
 			project.Command{
-				aptLayerCmdInstall,
-				[]string{"DEBIAN_FRONTEND=noninteractive"},
-				aptInstall})
+				Name: aptLayerCmdInstall,
+				Envs: []string{"DEBIAN_FRONTEND=noninteractive"},
+				Args: aptInstall})
 		cmds = &aptLayer.Commands[len(aptLayer.Commands)-1]
 	} else {
 		n := aptNames